@@ -0,0 +1,188 @@
+package main
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+/*
+	Bounded is Memo4 with a limited cache, for bodies of HTTP responses that can not
+	all be kept. Ready entries are kept in a list from the most to the least
+	recently used, and the least recently used go first when there are more than
+	MaxEntries of them or their values take more than MaxBytes. An entry expires
+	TTL after it was computed, and an error ErrorTTL after, so a failed fetch is
+	retried soon; with no ErrorTTL errors are not kept at all.
+
+	Entries being computed are in the cache but not in the list: callers of the same
+	key wait on entry.ready as in Memo4, and cancel the computation when they all
+	give up. An entry invalidated while being computed is delivered to its callers
+	but not kept.
+*/
+
+// BoundedOptions limit the cache of a Bounded memo, zero means no limit
+type BoundedOptions struct {
+	MaxEntries int
+	MaxBytes   int64
+	Size       func(value interface{}) int64 // by default the length of []byte and string values
+	TTL        time.Duration
+	ErrorTTL   time.Duration // zero means errors are not cached
+}
+
+// Bounded struct
+type Bounded struct {
+	f     Func4
+	opts  BoundedOptions
+	now   func() time.Time
+	mu    sync.Mutex
+	cache map[string]*boundedEntry
+	lru   *list.List // of ready entries, most recently used first
+	bytes int64
+}
+
+type boundedEntry struct {
+	entry
+	key     string
+	size    int64
+	expires time.Time     // zero for never
+	elem    *list.Element // nil while being computed
+}
+
+// NewBounded function
+func NewBounded(f Func4, opts BoundedOptions) *Bounded {
+	if opts.Size == nil {
+		opts.Size = valueSize
+	}
+	return &Bounded{
+		f:     f,
+		opts:  opts,
+		now:   time.Now,
+		cache: make(map[string]*boundedEntry),
+		lru:   list.New(),
+	}
+}
+
+func valueSize(value interface{}) int64 {
+	switch v := value.(type) {
+	case []byte:
+		return int64(len(v))
+	case string:
+		return int64(len(v))
+	}
+	return 0
+}
+
+// Get method
+func (memo *Bounded) Get(ctx context.Context, key string) (interface{}, error) {
+	memo.mu.Lock()
+	e := memo.cache[key]
+	if e != nil && e.elem != nil {
+		if !e.expires.IsZero() && !memo.now().Before(e.expires) {
+			memo.remove(e)
+			e = nil
+		} else {
+			memo.lru.MoveToFront(e.elem)
+			memo.mu.Unlock()
+			return e.res.value, e.res.err
+		}
+	}
+	if e == nil {
+		fctx, cancel := context.WithCancel(context.Background())
+		e = &boundedEntry{entry: entry{ready: make(chan struct{}), cancel: cancel}, key: key}
+		memo.cache[key] = e
+		go memo.call(fctx, e)
+	}
+	e.waiters++
+	memo.mu.Unlock()
+
+	select {
+	case <-e.ready:
+		return e.res.value, e.res.err
+	case <-ctx.Done():
+		memo.mu.Lock()
+		e.waiters--
+		if e.waiters == 0 && !isReady(e.ready) {
+			e.cancel()
+			memo.remove(e)
+		}
+		memo.mu.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+func (memo *Bounded) call(ctx context.Context, e *boundedEntry) {
+	e.res.value, e.res.err = memo.f(ctx, e.key)
+	memo.mu.Lock()
+	switch {
+	case memo.cache[e.key] != e: // invalidated or cancelled meanwhile
+	case ctx.Err() != nil || isCancellation(e.res.err):
+		memo.remove(e)
+	case e.res.err != nil && memo.opts.ErrorTTL <= 0:
+		memo.remove(e)
+	default:
+		ttl := memo.opts.TTL
+		if e.res.err != nil {
+			ttl = memo.opts.ErrorTTL
+		}
+		if ttl > 0 {
+			e.expires = memo.now().Add(ttl)
+		}
+		if e.res.err == nil {
+			e.size = memo.opts.Size(e.res.value)
+		}
+		e.elem = memo.lru.PushFront(e)
+		memo.bytes += e.size
+		memo.evict()
+	}
+	memo.mu.Unlock()
+	e.cancel()
+	close(e.ready)
+}
+
+// evict removes the least recently used entries until the cache is within its
+// limits
+func (memo *Bounded) evict() {
+	for memo.lru.Len() > 0 &&
+		(memo.opts.MaxEntries > 0 && memo.lru.Len() > memo.opts.MaxEntries ||
+			memo.opts.MaxBytes > 0 && memo.bytes > memo.opts.MaxBytes) {
+		memo.remove(memo.lru.Back().Value.(*boundedEntry))
+	}
+}
+
+// remove forgets e, which must be in the cache or being computed
+func (memo *Bounded) remove(e *boundedEntry) {
+	if memo.cache[e.key] == e {
+		delete(memo.cache, e.key)
+	}
+	if e.elem != nil {
+		memo.lru.Remove(e.elem)
+		memo.bytes -= e.size
+		e.elem = nil
+	}
+}
+
+// Invalidate forgets key, so the next Get computes it again
+func (memo *Bounded) Invalidate(key string) {
+	memo.mu.Lock()
+	if e := memo.cache[key]; e != nil {
+		memo.remove(e)
+	}
+	memo.mu.Unlock()
+}
+
+// Purge forgets every key
+func (memo *Bounded) Purge() {
+	memo.mu.Lock()
+	memo.cache = make(map[string]*boundedEntry)
+	memo.lru.Init()
+	memo.bytes = 0
+	memo.mu.Unlock()
+}
+
+// Len returns the number of values kept, and their bytes
+func (memo *Bounded) Len() (int, int64) {
+	memo.mu.Lock()
+	defer memo.mu.Unlock()
+	return memo.lru.Len(), memo.bytes
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// counting returns a function giving key repeated size times, that fails for keys
+// starting with "bad"
+func counting(calls *int32, size int) Func4 {
+	return func(ctx context.Context, key string) (interface{}, error) {
+		atomic.AddInt32(calls, 1)
+		if strings.HasPrefix(key, "bad") {
+			return nil, errors.New("bad key " + key)
+		}
+		return []byte(strings.Repeat(key, size)), nil
+	}
+}
+
+func TestBoundedLRU(t *testing.T) {
+	var calls int32
+	memo := NewBounded(counting(&calls, 1), BoundedOptions{MaxEntries: 2})
+	ctx := context.Background()
+	for _, key := range []string{"a", "b", "a", "c", "a", "b"} {
+		if v, err := memo.Get(ctx, key); err != nil || string(v.([]byte)) != key {
+			t.Fatalf("Get(%s) = %v, %v", key, v, err)
+		}
+	}
+	// b was the least recently used when c came, and c when b came again
+	if calls != 4 {
+		t.Errorf("%d calls, want 4", calls)
+	}
+	if n, _ := memo.Len(); n != 2 {
+		t.Errorf("%d entries, want 2", n)
+	}
+}
+
+func TestBoundedBytes(t *testing.T) {
+	var calls int32
+	memo := NewBounded(counting(&calls, 10), BoundedOptions{MaxBytes: 25})
+	ctx := context.Background()
+	for _, key := range []string{"a", "b", "c"} {
+		memo.Get(ctx, key)
+	}
+	if n, bytes := memo.Len(); n != 2 || bytes != 20 {
+		t.Errorf("%d entries of %d bytes, want 2 of 20", n, bytes)
+	}
+	memo.Get(ctx, "aaa") // 30 bytes, delivered but not kept
+	if n, bytes := memo.Len(); n != 0 || bytes != 0 {
+		t.Errorf("%d entries of %d bytes after a value too big, want none", n, bytes)
+	}
+}
+
+func TestBoundedTTL(t *testing.T) {
+	var calls int32
+	now := time.Unix(0, 0)
+	memo := NewBounded(counting(&calls, 1), BoundedOptions{TTL: time.Minute, ErrorTTL: time.Second})
+	memo.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	memo.Get(ctx, "a")
+	memo.Get(ctx, "bad")
+	now = now.Add(2 * time.Second)
+	memo.Get(ctx, "a")
+	if _, err := memo.Get(ctx, "bad"); err == nil {
+		t.Errorf("Get(bad) did not fail")
+	}
+	if calls != 3 {
+		t.Errorf("%d calls, want 3: the error expired, not the value", calls)
+	}
+	now = now.Add(time.Minute)
+	memo.Get(ctx, "a")
+	if calls != 4 {
+		t.Errorf("%d calls, want 4 after the value expired", calls)
+	}
+
+	// without ErrorTTL errors are not kept
+	calls = 0
+	memo = NewBounded(counting(&calls, 1), BoundedOptions{})
+	memo.Get(ctx, "bad")
+	memo.Get(ctx, "bad")
+	if calls != 2 {
+		t.Errorf("%d calls for an error, want 2", calls)
+	}
+}
+
+func TestBoundedInvalidate(t *testing.T) {
+	var calls int32
+	memo := NewBounded(counting(&calls, 1), BoundedOptions{})
+	ctx := context.Background()
+	memo.Get(ctx, "a")
+	memo.Get(ctx, "b")
+	memo.Invalidate("a")
+	memo.Get(ctx, "a")
+	memo.Get(ctx, "b")
+	if calls != 3 {
+		t.Errorf("%d calls after Invalidate, want 3", calls)
+	}
+	memo.Purge()
+	memo.Get(ctx, "a")
+	memo.Get(ctx, "b")
+	if n, _ := memo.Len(); calls != 5 || n != 2 {
+		t.Errorf("%d calls and %d entries after Purge, want 5 and 2", calls, n)
+	}
+}
+
+func TestBoundedDuplicateSuppression(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	memo := NewBounded(func(ctx context.Context, key string) (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return key, nil
+	}, BoundedOptions{MaxEntries: 1})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if v, _ := memo.Get(context.Background(), "k"); v != "k" {
+				t.Errorf("Get = %v", v)
+			}
+		}()
+	}
+	for waiting := 0; waiting < 10; time.Sleep(time.Millisecond) {
+		memo.mu.Lock()
+		if e := memo.cache["k"]; e != nil {
+			waiting = e.waiters
+		}
+		memo.mu.Unlock()
+	}
+	memo.Invalidate("k") // the callers waiting still get the value
+	close(release)
+	wg.Wait()
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("%d calls for 10 concurrent Gets, want 1", n)
+	}
+	if n, _ := memo.Len(); n != 0 {
+		t.Errorf("value invalidated while computed was kept")
+	}
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type ctxMemo interface {
+	Get(ctx context.Context, key string) (interface{}, error)
+}
+
+// blocking returns a function that waits for release or for its context, and
+// reports on cancelled each context it saw done
+func blocking(calls *int32, release <-chan struct{}, cancelled chan<- string) Func4 {
+	return func(ctx context.Context, key string) (interface{}, error) {
+		atomic.AddInt32(calls, 1)
+		select {
+		case <-release:
+			return key + "!", nil
+		case <-ctx.Done():
+			cancelled <- key
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// forEachCtxMemo runs test against Memo4 and Memo5
+func forEachCtxMemo(t *testing.T, test func(t *testing.T, newMemo func(Func4) ctxMemo)) {
+	t.Run("Memo4", func(t *testing.T) {
+		test(t, func(f Func4) ctxMemo { return New4(f) })
+	})
+	t.Run("Memo5", func(t *testing.T) {
+		test(t, func(f Func4) ctxMemo {
+			m := New5(Func5(f))
+			t.Cleanup(m.Close)
+			return m
+		})
+	})
+}
+
+func TestGetCancelled(t *testing.T) {
+	forEachCtxMemo(t, func(t *testing.T, newMemo func(Func4) ctxMemo) {
+		var calls int32
+		release := make(chan struct{})
+		cancelled := make(chan string, 10)
+		memo := newMemo(blocking(&calls, release, cancelled))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		if _, err := memo.Get(ctx, "a"); err != context.DeadlineExceeded {
+			t.Errorf("Get = %v, want deadline exceeded", err)
+		}
+		select {
+		case <-cancelled:
+		case <-time.After(time.Second):
+			t.Fatalf("computation not cancelled when its only caller left")
+		}
+
+		// the cancelled result was not kept
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			if v, err := memo.Get(context.Background(), "a"); v != "a!" || err != nil {
+				t.Errorf("Get after cancellation = %v, %v", v, err)
+			}
+		}()
+		waitCalls(t, &calls, 2)
+		close(release)
+		<-done
+	})
+}
+
+func TestGetOneOfTwoCancelled(t *testing.T) {
+	forEachCtxMemo(t, func(t *testing.T, newMemo func(Func4) ctxMemo) {
+		var calls int32
+		release := make(chan struct{})
+		cancelled := make(chan string, 10)
+		memo := newMemo(blocking(&calls, release, cancelled))
+
+		got := make(chan interface{})
+		go func() {
+			v, _ := memo.Get(context.Background(), "b")
+			got <- v
+		}()
+		waitCalls(t, &calls, 1)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		if _, err := memo.Get(ctx, "b"); err != context.DeadlineExceeded {
+			t.Errorf("Get with a short deadline = %v", err)
+		}
+		close(release)
+		if v := <-got; v != "b!" {
+			t.Errorf("remaining caller got %v", v)
+		}
+		if len(cancelled) > 0 {
+			t.Errorf("computation cancelled while a caller was waiting")
+		}
+		if n := atomic.LoadInt32(&calls); n != 1 {
+			t.Errorf("%d calls, want 1", n)
+		}
+	})
+}
+
+func TestCancellationNotCached(t *testing.T) {
+	forEachCtxMemo(t, func(t *testing.T, newMemo func(Func4) ctxMemo) {
+		var calls int32
+		memo := newMemo(func(ctx context.Context, key string) (interface{}, error) {
+			if atomic.AddInt32(&calls, 1) == 1 {
+				return nil, context.DeadlineExceeded // a timeout of f itself
+			}
+			return key, nil
+		})
+		if _, err := memo.Get(context.Background(), "c"); err != context.DeadlineExceeded {
+			t.Errorf("first Get = %v", err)
+		}
+		if v, err := memo.Get(context.Background(), "c"); v != "c" || err != nil {
+			t.Errorf("second Get = %v, %v", v, err)
+		}
+		if v, _ := memo.Get(context.Background(), "c"); v != "c" || atomic.LoadInt32(&calls) != 2 {
+			t.Errorf("third Get = %v after %d calls, want a cached c", v, atomic.LoadInt32(&calls))
+		}
+	})
+}
+
+// waitCalls waits until f has been called n times
+func waitCalls(t *testing.T, calls *int32, n int32) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for atomic.LoadInt32(calls) < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d calls, want %d", atomic.LoadInt32(calls), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// A leave that arrives after its entry was cancelled and computed again under the
+// same key does not count against the new entry.
+func TestMemo5StaleLeave(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	cancelled := make(chan string, 10)
+	memo := New5(Func5(blocking(&calls, release, cancelled)))
+	defer memo.Close()
+
+	response := make(chan *entry5, 1)
+	memo.requests <- request{key: "d", response: response}
+	old := <-response
+	memo.requests <- request{key: "d", leave: old}
+	<-cancelled
+
+	got := make(chan interface{})
+	go func() {
+		v, _ := memo.Get(context.Background(), "d")
+		got <- v
+	}()
+	waitCalls(t, &calls, 2)
+	memo.requests <- request{key: "d", leave: old}
+	select {
+	case <-cancelled:
+		t.Errorf("stale leave cancelled the new computation")
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(release)
+	if v := <-got; v != "d!" {
+		t.Errorf("Get after a stale leave = %v, want d!", v)
+	}
+}
+
+// A caller that gives up after Close does not block, and a Get after Close fails.
+func TestMemo5GetAfterClose(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	defer close(release)
+	memo := New5(func(ctx context.Context, key string) (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release // deaf to the cancellation of Close
+		return key, nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	got := make(chan error)
+	go func() {
+		_, err := memo.Get(ctx, "a")
+		got <- err
+	}()
+	waitCalls(t, &calls, 1)
+	memo.Close()
+	cancel()
+	select {
+	case err := <-got:
+		if err != context.Canceled {
+			t.Errorf("Get cancelled after Close = %v, want cancelled", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Get cancelled after Close did not return")
+	}
+	if _, err := memo.Get(context.Background(), "a"); err != ErrClosed {
+		t.Errorf("Get after Close = %v, want ErrClosed", err)
+	}
+}
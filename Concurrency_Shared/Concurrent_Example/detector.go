@@ -1,6 +1,9 @@
 package main 
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"io/ioutil"
@@ -131,6 +134,14 @@ func New3(f Func3) *Memo3 {
 
 // memo4 
 
+/*
+	Ejercicio 9.3: Get takes a context, and so does the function. A caller whose
+	context is done returns ctx.Err() at once, without waiting for the result. The
+	function runs in its own goroutine with a context of its own, cancelled when
+	every caller waiting for it has gone. The entry of a cancelled computation is
+	removed from the cache, so the next Get computes it again.
+*/
+
 // Memo4 struct
 type Memo4 struct {
 	f Func4 
@@ -146,26 +157,72 @@ type result4 struct {
 type entry struct {
 	res result4 
 	ready chan struct{}
+	waiters int // callers waiting for ready, guarded by the mutex of the memo
+	cancel context.CancelFunc
 }
 
 // Func4 type function
-type Func4 func(key string) (value interface{},err error)
+type Func4 func(ctx context.Context, key string) (value interface{},err error)
 
 // Get method
-func (memo4 *Memo4) Get(key string) (value interface{},err error) {
+func (memo4 *Memo4) Get(ctx context.Context, key string) (value interface{},err error) {
 	memo4.mu.Lock()
 	e := memo4.cache[key]
 	if e == nil {
-		e = &entry{ready:make(chan struct{})}
+		fctx, cancel := context.WithCancel(context.Background())
+		e = &entry{ready:make(chan struct{}), cancel:cancel}
 		memo4.cache[key] = e
+		go memo4.call(fctx, e, key)
+	}
+	e.waiters++
+	memo4.mu.Unlock()
+
+	select {
+	case <-e.ready:
+		return e.res.value,e.res.err
+	case <-ctx.Done():
+		memo4.mu.Lock()
+		e.waiters--
+		if e.waiters == 0 && !isReady(e.ready) {
+			e.cancel()
+			memo4.forget(key, e)
+		}
 		memo4.mu.Unlock()
-		e.res.value, e.res.err = memo4.f(key)
-		close(e.ready)
-	}else {
+		return nil, ctx.Err()
+	}
+}
+
+func (memo4 *Memo4) call(ctx context.Context, e *entry, key string) {
+	e.res.value, e.res.err = memo4.f(ctx, key)
+	if ctx.Err() != nil || isCancellation(e.res.err) {
+		memo4.mu.Lock()
+		memo4.forget(key, e)
 		memo4.mu.Unlock()
-		<-e.ready
 	}
-	return e.res.value,e.res.err
+	e.cancel()
+	close(e.ready)
+}
+
+// forget removes e from the cache, unless another entry took its place
+func (memo4 *Memo4) forget(key string, e *entry) {
+	if memo4.cache[key] == e {
+		delete(memo4.cache, key)
+	}
+}
+
+// isReady reports whether ready is closed
+func isReady(ready chan struct{}) bool {
+	select {
+	case <-ready:
+		return true
+	default:
+		return false
+	}
+}
+
+// isCancellation reports whether err comes from a done context
+func isCancellation(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
 }
 
 // New4 function
@@ -176,13 +233,32 @@ func New4(f Func4) *Memo4 {
 
 // memo5 
 
+/*
+	The monitor goroutine of Memo5 counts the callers waiting for each entry too.
+	The server answers a request with the entry, and the caller waits for it to be
+	ready. A caller that gives up sends a request with leave set to that entry;
+	when the last one leaves before the result is ready, the server cancels the
+	computation and forgets the entry. A leave for an entry no longer cached, as
+	one computed again under the same key, is ignored. A cancelled result found in
+	the cache is computed again. Once the server is closed, Get fails with
+	ErrClosed and a leave is dropped.
+*/
+
+// ErrClosed is the error of a Get after Close
+var ErrClosed = errors.New("memo closed")
+
 type request struct {
 	key string 
-	response chan<- result5
+	response chan<- *entry5
+	leave *entry5 // the entry the caller stopped waiting for
 }
 
 // Memo5 struct
-type Memo5 struct {requests chan request}
+type Memo5 struct {
+	requests chan request
+	quit chan struct{} // closed by Close
+	done chan struct{} // closed when the server returns
+}
 
 type result5 struct {
 	value interface{}
@@ -192,57 +268,103 @@ type result5 struct {
 type entry5 struct {
 	res result5 
 	ready chan struct{}
+	waiters int // owned by the server goroutine
+	cancel context.CancelFunc
 }
 
 // New5 function
 func New5(f Func5) *Memo5 {
-	memo5 := &Memo5{requests:make(chan request)}
+	memo5 := &Memo5{requests:make(chan request), quit:make(chan struct{}), done:make(chan struct{})}
 	go memo5.server(f)
 	return memo5
 }
 
 // Func5 function
-type Func5 func(key string) (value interface{},err error)
+type Func5 func(ctx context.Context, key string) (value interface{},err error)
 
 // Get method
-func (memo5 *Memo5) Get(key string) (interface{},error) {
-	response := make(chan result5)
-	memo5.requests <- request{key,response}
-	res := <-response 
-	return res.value,res.err
+func (memo5 *Memo5) Get(ctx context.Context, key string) (interface{},error) {
+	response := make(chan *entry5, 1) // the server never blocks on a caller
+	select {
+	case memo5.requests <- request{key:key, response:response}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-memo5.done:
+		return nil, ErrClosed
+	}
+	e := <-response
+	select {
+	case <-e.ready:
+		return e.res.value,e.res.err
+	case <-ctx.Done():
+		select {
+		case memo5.requests <- request{key:key, leave:e}:
+		case <-memo5.done: // the computation is cancelled already
+		}
+		return nil, ctx.Err()
+	}
 }
 
-// Close method
-func (memo5 *Memo5) Close() {close(memo5.requests)}
+// Close stops the server, cancelling the computations in flight, and waits for
+// it to return. A Get after Close returns ErrClosed.
+func (memo5 *Memo5) Close() {
+	close(memo5.quit)
+	<-memo5.done
+}
 
 func (memo5 *Memo5) server(f Func5) {
 	cache := make(map[string]*entry5)
-	for req := range memo5.requests {
+	for {
+		var req request
+		select {
+		case req = <-memo5.requests:
+		case <-memo5.quit:
+			for _, e := range cache {
+				e.cancel()
+			}
+			close(memo5.done)
+			return
+		}
 		e := cache[req.key]
+		if req.leave != nil {
+			if e == req.leave && !isReady(e.ready) {
+				e.waiters--
+				if e.waiters == 0 {
+					e.cancel()
+					delete(cache, req.key)
+				}
+			}
+			continue
+		}
+		if e != nil && isReady(e.ready) && isCancellation(e.res.err) {
+			e = nil
+		}
 		if e == nil {
-			e = &entry5{ready:make(chan struct{})}
+			ctx, cancel := context.WithCancel(context.Background())
+			e = &entry5{ready:make(chan struct{}), cancel:cancel}
 			cache[req.key] = e
-			go e.call(f,req.key)
+			go e.call(ctx, f, req.key)
 		}
-		go e.deliver(req.response)
+		e.waiters++
+		req.response <- e
 	}
 }
 
-func (e *entry5) call(f Func5,key string) {
-	e.res.value, e.res.err = f(key)
+func (e *entry5) call(ctx context.Context, f Func5,key string) {
+	e.res.value, e.res.err = f(ctx, key)
+	e.cancel()
 	close(e.ready)
 }
 
-func (e *entry5) deliver(response chan<- result5) {
-	<-e.ready
-	response <-e.res
-}
-
 
 // Funcion a memorizar resultado
 
-func httpGetBody(url string) (interface{},error) {
-	resp, err := http.Get(url)
+func httpGetBody(ctx context.Context, url string) (interface{},error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil,err
+	}
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return nil,err 
 	}
@@ -272,15 +394,22 @@ func incomingURLs() <-chan string {
 
 
 
+var cacheDir = flag.String("cache", "", "directory keeping the responses between runs")
+
 func main() {
-   	men := New5(httpGetBody)
+	flag.Parse()
+	fetch := Func5(httpGetBody)
+	if *cacheDir != "" {
+		fetch = (&DiskCache{Dir: *cacheDir}).Get
+	}
+   	men := New5(fetch)
    for url := range incomingURLs() {
 		start := time.Now()
-		value, err := men.Get(url)
+		value, err := men.Get(context.Background(), url)
 		if err != nil {
 			log.Print(err)
 		}
 		fmt.Printf("%s, %s, %d bytes\n", url,time.Since(start),len(value.([]byte)))
    }
 
-}
\ No newline at end of file
+}
\ No newline at end of file
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+/*
+	DiskCache is httpGetBody with the responses kept in a directory, so they outlive
+	the process. Its Get has the type of Func4, to sit under any of the memos:
+
+	memo := New4((&DiskCache{Dir: dir}).Get)
+
+	Each URL has one file, named by the SHA-256 of the URL, holding a line of JSON
+	with the status and headers followed by the body. A file is written under a
+	temporary name and renamed, so processes sharing the directory only ever read
+	whole files.
+
+	A response is fresh for the max-age of its Cache-Control header, and served
+	from disk meanwhile. After that, or with no-cache, it is revalidated with
+	If-None-Match and If-Modified-Since, and a 304 Not Modified keeps the body on
+	disk. Responses with no-store, and those that are not 200 OK, are not kept.
+	A body that cannot be written to disk is still returned, the failed writes
+	are counted by StoreErrors.
+*/
+
+// DiskCache struct
+type DiskCache struct {
+	Dir    string
+	Client *http.Client // nil means http.DefaultClient
+	now    func() time.Time
+
+	storeErrors int64 // atomic
+}
+
+// diskEntry is the first line of a cache file
+type diskEntry struct {
+	URL    string
+	Status int
+	Header http.Header
+	Stored time.Time
+}
+
+// Get returns the body of url, from the disk when it is fresh or not modified
+func (c *DiskCache) Get(ctx context.Context, url string) (interface{}, error) {
+	now := time.Now
+	if c.now != nil {
+		now = c.now
+	}
+	meta, body, err := c.load(url)
+	if err == nil && now().Before(meta.Stored.Add(maxAge(meta.Header))) {
+		return body, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if meta != nil {
+		if etag := meta.Header.Get("ETag"); etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		if modified := meta.Header.Get("Last-Modified"); modified != "" {
+			req.Header.Set("If-Modified-Since", modified)
+		}
+	}
+	client := c.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && meta != nil {
+		for name, values := range resp.Header { // a 304 may update the headers
+			meta.Header[name] = values
+		}
+		meta.Stored = now()
+		c.keep(meta, body)
+		return body, nil
+	}
+	body, err = io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK || hasDirective(resp.Header, "no-store") {
+		if meta != nil {
+			os.Remove(c.path(url))
+		}
+		return body, nil
+	}
+	entry := &diskEntry{URL: url, Status: resp.StatusCode, Header: resp.Header, Stored: now()}
+	c.keep(entry, body)
+	return body, nil
+}
+
+// StoreErrors returns the number of responses that could not be written to Dir
+func (c *DiskCache) StoreErrors() int64 {
+	return atomic.LoadInt64(&c.storeErrors)
+}
+
+// keep stores an entry, counting a failure instead of failing the Get
+func (c *DiskCache) keep(meta *diskEntry, body []byte) {
+	if err := c.store(meta, body); err != nil {
+		atomic.AddInt64(&c.storeErrors, 1)
+	}
+}
+
+// path returns the file of url
+func (c *DiskCache) path(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return filepath.Join(c.Dir, hex.EncodeToString(sum[:]))
+}
+
+// load reads the entry of url
+func (c *DiskCache) load(url string) (*diskEntry, []byte, error) {
+	f, err := os.Open(c.path(url))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	line, err := r.ReadBytes('\n')
+	if err != nil {
+		return nil, nil, err
+	}
+	var meta diskEntry
+	if err := json.Unmarshal(line, &meta); err != nil {
+		return nil, nil, err
+	}
+	if meta.URL != url {
+		return nil, nil, fmt.Errorf("%s holds %s", f.Name(), meta.URL)
+	}
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &meta, body, nil
+}
+
+// store writes an entry to a temporary file and renames it over the old one
+func (c *DiskCache) store(meta *diskEntry, body []byte) error {
+	if err := os.MkdirAll(c.Dir, 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(c.Dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // fails once renamed
+	line, err := json.Marshal(meta)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	w := bufio.NewWriter(tmp)
+	w.Write(line)
+	w.WriteByte('\n')
+	w.Write(body)
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), c.path(meta.URL))
+}
+
+// maxAge returns how long a response stays fresh, zero for no-cache or without
+// a max-age
+func maxAge(header http.Header) time.Duration {
+	if hasDirective(header, "no-cache") {
+		return 0
+	}
+	for _, directive := range cacheControl(header) {
+		if value := strings.TrimPrefix(directive, "max-age="); value != directive {
+			if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+				return time.Duration(seconds) * time.Second
+			}
+		}
+	}
+	return 0
+}
+
+func hasDirective(header http.Header, name string) bool {
+	for _, directive := range cacheControl(header) {
+		if directive == name {
+			return true
+		}
+	}
+	return false
+}
+
+// cacheControl returns the directives of the Cache-Control headers, in lower case
+func cacheControl(header http.Header) []string {
+	var directives []string
+	for _, value := range header.Values("Cache-Control") {
+		for _, d := range strings.Split(value, ",") {
+			directives = append(directives, strings.ToLower(strings.TrimSpace(d)))
+		}
+	}
+	return directives
+}
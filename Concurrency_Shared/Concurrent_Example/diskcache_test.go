@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// origin is a server of a body with the given headers, that answers conditional
+// requests and counts requests and full responses
+type origin struct {
+	header           http.Header
+	body             string
+	requests, bodies int32
+}
+
+func (o *origin) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	atomic.AddInt32(&o.requests, 1)
+	for name, values := range o.header {
+		w.Header()[name] = values
+	}
+	etag := o.header.Get("ETag")
+	modified := o.header.Get("Last-Modified")
+	if etag != "" && r.Header.Get("If-None-Match") == etag ||
+		etag == "" && modified != "" && r.Header.Get("If-Modified-Since") == modified {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	atomic.AddInt32(&o.bodies, 1)
+	w.Write([]byte(o.body))
+}
+
+func TestDiskCache(t *testing.T) {
+	for _, test := range []struct {
+		name             string
+		header           http.Header
+		requests, bodies int32 // after three Gets
+		stored           bool
+	}{
+		{"max-age", http.Header{"Cache-Control": {"max-age=60"}}, 1, 1, true},
+		{"etag", http.Header{"Etag": {`"v1"`}}, 3, 1, true},
+		{"last-modified", http.Header{"Last-Modified": {"Mon, 02 Jan 2006 15:04:05 GMT"}}, 3, 1, true},
+		{"no-cache", http.Header{"Cache-Control": {"max-age=60, no-cache"}, "Etag": {`"v1"`}}, 3, 1, true},
+		{"no-store", http.Header{"Cache-Control": {"no-store"}, "Etag": {`"v1"`}}, 3, 3, false},
+		{"no validator", nil, 3, 3, true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			o := &origin{header: test.header, body: "hello " + test.name}
+			server := httptest.NewServer(o)
+			defer server.Close()
+
+			cache := &DiskCache{Dir: t.TempDir()}
+			for i := 0; i < 3; i++ {
+				v, err := cache.Get(context.Background(), server.URL)
+				if b, _ := v.([]byte); err != nil || string(b) != o.body {
+					t.Fatalf("Get %d = %q, %v", i, v, err)
+				}
+			}
+			if o.requests != test.requests || o.bodies != test.bodies {
+				t.Errorf("%d requests and %d bodies, want %d and %d",
+					o.requests, o.bodies, test.requests, test.bodies)
+			}
+			if _, err := os.Stat(cache.path(server.URL)); (err == nil) != test.stored {
+				t.Errorf("stored = %v, want %v", err == nil, test.stored)
+			}
+		})
+	}
+}
+
+func TestDiskCacheExpiry(t *testing.T) {
+	o := &origin{header: http.Header{"Cache-Control": {"max-age=60"}, "Etag": {`"v1"`}}, body: "a"}
+	server := httptest.NewServer(o)
+	defer server.Close()
+	dir := t.TempDir()
+	now := time.Unix(0, 0)
+	clock := func() time.Time { return now }
+	ctx := context.Background()
+
+	(&DiskCache{Dir: dir, now: clock}).Get(ctx, server.URL)
+	// another process sharing the directory
+	other := &DiskCache{Dir: dir, now: clock}
+	if v, _ := other.Get(ctx, server.URL); string(v.([]byte)) != "a" || o.requests != 1 {
+		t.Errorf("Get from a new cache = %q after %d requests, want a after 1", v, o.requests)
+	}
+	now = now.Add(2 * time.Minute)
+	other.Get(ctx, server.URL)
+	if o.requests != 2 || o.bodies != 1 {
+		t.Errorf("%d requests and %d bodies once stale, want a revalidation", o.requests, o.bodies)
+	}
+	// the 304 made it fresh again
+	other.Get(ctx, server.URL)
+	if o.requests != 2 {
+		t.Errorf("%d requests after revalidation, want 2", o.requests)
+	}
+
+	// a new version replaces the old one
+	now = now.Add(2 * time.Minute)
+	o.header.Set("Etag", `"v2"`)
+	o.body = "b"
+	if v, _ := other.Get(ctx, server.URL); string(v.([]byte)) != "b" {
+		t.Errorf("Get after a change = %q, want b", v)
+	}
+}
+
+func TestDiskCacheErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "gone", http.StatusNotFound)
+	}))
+	defer server.Close()
+	cache := &DiskCache{Dir: t.TempDir()}
+	v, err := cache.Get(context.Background(), server.URL)
+	if b, _ := v.([]byte); err != nil || !strings.Contains(string(b), "gone") {
+		t.Errorf("Get = %q, %v", v, err)
+	}
+	if _, err := os.Stat(cache.path(server.URL)); err == nil {
+		t.Errorf("404 response stored")
+	}
+	if _, err := cache.Get(context.Background(), "http://\x00"); err == nil {
+		t.Errorf("Get of a bad URL did not fail")
+	}
+}
+
+func TestDiskCacheUnwritable(t *testing.T) {
+	server := httptest.NewServer(&origin{header: http.Header{"Etag": {`"v1"`}}, body: "a"})
+	defer server.Close()
+	// a directory under a file, since root may write to a read-only directory
+	file := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	cache := &DiskCache{Dir: filepath.Join(file, "cache")}
+	for i := 1; i <= 2; i++ {
+		v, err := cache.Get(context.Background(), server.URL)
+		if b, _ := v.([]byte); err != nil || string(b) != "a" {
+			t.Errorf("Get %d = %q, %v, want a", i, v, err)
+		}
+		if n := cache.StoreErrors(); n != int64(i) {
+			t.Errorf("%d store errors after Get %d", n, i)
+		}
+	}
+}
+
+func TestDiskCacheConcurrent(t *testing.T) {
+	o := &origin{header: http.Header{"Etag": {`"v1"`}}, body: strings.Repeat("x", 1<<16)}
+	server := httptest.NewServer(o)
+	defer server.Close()
+	dir := t.TempDir()
+
+	// caches of their own, as separate processes would have
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cache := &DiskCache{Dir: dir}
+			for j := 0; j < 5; j++ {
+				v, err := cache.Get(context.Background(), server.URL)
+				if b, _ := v.([]byte); err != nil || len(b) != len(o.body) {
+					t.Errorf("Get = %d bytes, %v", len(b), err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	files, err := os.ReadDir(dir)
+	if err != nil || len(files) != 1 {
+		t.Errorf("%d files left in the cache, want 1: %v", len(files), err)
+	}
+
+	memo := New4((&DiskCache{Dir: dir}).Get)
+	v, err := memo.Get(context.Background(), server.URL)
+	if b, _ := v.([]byte); err != nil || len(b) != len(o.body) {
+		t.Errorf("Memo4 over the disk cache = %d bytes, %v", len(b), err)
+	}
+}
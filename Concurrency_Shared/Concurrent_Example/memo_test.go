@@ -0,0 +1,322 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fake is a function to memoize that counts its calls by key, takes latency to
+// return and, when gate is not nil, waits for gate to be closed. Keys starting
+// with "err" fail with errFake.
+type fake struct {
+	latency   time.Duration
+	gate      chan struct{}
+	mu        sync.Mutex
+	calls     map[string]int
+	cancelled int
+}
+
+var errFake = errors.New("fake failure")
+
+func newFake(latency time.Duration, gated bool) *fake {
+	f := &fake{latency: latency, calls: make(map[string]int)}
+	if gated {
+		f.gate = make(chan struct{})
+	}
+	return f
+}
+
+func (f *fake) Get(ctx context.Context, key string) (interface{}, error) {
+	f.mu.Lock()
+	f.calls[key]++
+	f.mu.Unlock()
+	var gate <-chan struct{} = f.gate
+	if gate == nil {
+		closed := make(chan struct{})
+		close(closed)
+		gate = closed
+	}
+	select {
+	case <-gate:
+		time.Sleep(f.latency)
+	case <-ctx.Done():
+		f.mu.Lock()
+		f.cancelled++
+		f.mu.Unlock()
+		return nil, ctx.Err()
+	}
+	if strings.HasPrefix(key, "err") {
+		return nil, fmt.Errorf("%s: %w", key, errFake)
+	}
+	return "value of " + key, nil
+}
+
+// callsOf returns the calls of key
+func (f *fake) callsOf(key string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[key]
+}
+
+// total returns the calls of every key
+func (f *fake) total() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := 0
+	for _, calls := range f.calls {
+		n += calls
+	}
+	return n
+}
+
+// waitTotal waits until f has been called n times
+func (f *fake) waitTotal(t *testing.T, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for f.total() < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d calls, want %d", f.total(), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// How a memo behaves under concurrent Gets of the same key
+const (
+	unsafeMemo      = iota // concurrent Gets race on the map
+	serialMemo             // f runs with the lock held, one call at a time
+	duplicatingMemo        // every Get that misses calls f
+	dedupMemo              // one call per key, the other Gets wait for it
+)
+
+type memoCase struct {
+	name       string
+	concurrent int
+	new        func(f *fake) (get func(key string) (interface{}, error), close func())
+}
+
+var memoCases = []memoCase{
+	{"Memo1", unsafeMemo, func(f *fake) (func(string) (interface{}, error), func()) {
+		return New1(withoutContext(f)).Get, func() {}
+	}},
+	{"Memo2", serialMemo, func(f *fake) (func(string) (interface{}, error), func()) {
+		return New2(Func2(withoutContext(f))).Get, func() {}
+	}},
+	{"Memo3", duplicatingMemo, func(f *fake) (func(string) (interface{}, error), func()) {
+		return New3(Func3(withoutContext(f))).Get, func() {}
+	}},
+	{"Memo4", dedupMemo, func(f *fake) (func(string) (interface{}, error), func()) {
+		return withContext(New4(f.Get)), func() {}
+	}},
+	{"Memo5", dedupMemo, func(f *fake) (func(string) (interface{}, error), func()) {
+		memo := New5(f.Get)
+		return withContext(memo), memo.Close
+	}},
+}
+
+func withoutContext(f *fake) Func1 {
+	return func(key string) (interface{}, error) { return f.Get(context.Background(), key) }
+}
+
+func withContext(memo ctxMemo) func(string) (interface{}, error) {
+	return func(key string) (interface{}, error) { return memo.Get(context.Background(), key) }
+}
+
+// checkResult reports a result of Get other than the one of the fake function
+func checkResult(t *testing.T, key string, v interface{}, err error) {
+	t.Helper()
+	if strings.HasPrefix(key, "err") {
+		if v != nil || !errors.Is(err, errFake) || !strings.HasPrefix(err.Error(), key) {
+			t.Errorf("Get(%s) = %v, %v, want the fake error", key, v, err)
+		}
+	} else if v != "value of "+key || err != nil {
+		t.Errorf("Get(%s) = %v, %v", key, v, err)
+	}
+}
+
+func TestMemoSequential(t *testing.T) {
+	keys := []string{"a", "b", "err1", "a", "c", "err1", "b", "err2", "c", "a"}
+	for _, mc := range memoCases {
+		t.Run(mc.name, func(t *testing.T) {
+			f := newFake(0, false)
+			get, stop := mc.new(f)
+			defer stop()
+			for _, key := range keys {
+				v, err := get(key)
+				checkResult(t, key, v, err)
+			}
+			// errors are memoized as values are
+			for _, key := range []string{"a", "b", "c", "err1", "err2"} {
+				if n := f.callsOf(key); n != 1 {
+					t.Errorf("%d calls of %s, want 1", n, key)
+				}
+			}
+		})
+	}
+}
+
+func TestMemoConcurrent(t *testing.T) {
+	const n = 10 // Gets of each key
+	for _, keys := range [][]string{
+		{"a"},
+		{"a", "b", "c", "d"},
+		{"a", "err1", "b", "err2"},
+	} {
+		for _, mc := range memoCases {
+			t.Run(fmt.Sprintf("%s/%d keys", mc.name, len(keys)), func(t *testing.T) {
+				if mc.concurrent == unsafeMemo {
+					if os.Getenv(unsafeChildEnv) == "" {
+						expectCrash(t)
+					} else {
+						raceGets(mc, keys, n)
+					}
+					return
+				}
+				// Memo2 holds its lock while f runs, so f must return by itself
+				f := newFake(time.Millisecond, mc.concurrent != serialMemo)
+				get, stop := mc.new(f)
+				defer stop()
+
+				var wg sync.WaitGroup
+				for _, key := range keys {
+					for i := 0; i < n; i++ {
+						wg.Add(1)
+						go func(key string) {
+							defer wg.Done()
+							v, err := get(key)
+							checkResult(t, key, v, err)
+						}(key)
+					}
+				}
+				want := 1
+				if mc.concurrent == duplicatingMemo {
+					want = n // they all missed while the gate was closed
+				}
+				if f.gate != nil {
+					f.waitTotal(t, want*len(keys))
+					close(f.gate)
+				}
+				wg.Wait()
+				for _, key := range keys {
+					if calls := f.callsOf(key); calls != want {
+						t.Errorf("%d calls of %s for %d concurrent Gets, want %d", calls, key, n, want)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestMemo5Close(t *testing.T) {
+	const keys = 5
+	goroutines := runtime.NumGoroutine()
+	f := newFake(0, true)
+	memo := New5(f.Get)
+
+	var wg sync.WaitGroup
+	for i := 0; i < keys; i++ {
+		wg.Add(1)
+		go func(key string) {
+			defer wg.Done()
+			if _, err := memo.Get(context.Background(), key); err != context.Canceled {
+				t.Errorf("Get(%s) during Close = %v, want cancelled", key, err)
+			}
+		}(fmt.Sprint(i))
+	}
+	f.waitTotal(t, keys)
+
+	closed := make(chan struct{})
+	go func() {
+		memo.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("Close did not return")
+	}
+	wg.Wait()
+	if f.cancelled != keys {
+		t.Errorf("%d computations cancelled, want %d", f.cancelled, keys)
+	}
+
+	// the server and the computations are all gone
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > goroutines {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d goroutines after Close, want %d", runtime.NumGoroutine(), goroutines)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// unsafeChildEnv is set for the test binary run again by expectCrash
+const unsafeChildEnv = "MEMO_UNSAFE_CHILD"
+
+// expectCrash runs the test t again in a child process, with unsafeChildEnv set,
+// and checks that it fails on concurrent map writes or on a data race. The map
+// writes of a single CPU seldom overlap, so it is skipped unless the tests were
+// built with -race.
+func expectCrash(t *testing.T) {
+	t.Helper()
+	if !raceEnabled() {
+		t.Skip("needs the tests built with -race")
+	}
+	var run []string
+	for _, name := range strings.Split(t.Name(), "/") {
+		run = append(run, "^"+regexp.QuoteMeta(name)+"$")
+	}
+	cmd := exec.Command(os.Args[0], "-test.run="+strings.Join(run, "/"), "-test.count=1")
+	cmd.Env = append(os.Environ(), unsafeChildEnv+"=1")
+	out, err := cmd.CombinedOutput()
+	if err == nil {
+		t.Fatalf("concurrent Gets did not fail:\n%s", out)
+	}
+	if !strings.Contains(string(out), "concurrent map") && !strings.Contains(string(out), "DATA RACE") {
+		t.Errorf("concurrent Gets failed with %v, not on the map:\n%s", err, out)
+	}
+}
+
+// raceEnabled reports whether the test binary was built with -race
+func raceEnabled() bool {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return false
+	}
+	for _, s := range info.Settings {
+		if s.Key == "-race" {
+			return s.Value == "true"
+		}
+	}
+	return false
+}
+
+// raceGets makes n concurrent Gets of each key, over and over, until the memo of
+// mc crashes
+func raceGets(mc memoCase, keys []string, n int) {
+	for round := 0; round < 1000; round++ {
+		get, stop := mc.new(newFake(0, false))
+		var wg sync.WaitGroup
+		for _, key := range keys {
+			for i := 0; i < n; i++ {
+				wg.Add(1)
+				go func(key string) {
+					defer wg.Done()
+					get(key)
+				}(key)
+			}
+		}
+		wg.Wait()
+		stop()
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"hash/maphash"
+	"sync"
+	"time"
+)
+
+/*
+	Memo is Memo4 for any types of key and value, with its keys spread over shards
+	that each have a mutex of their own, so Gets of different keys seldom wait for
+	one another. The shard of a key is chosen by a hash; strings and integers are
+	hashed directly, other keys through their printed form, unless a hash function
+	is given.
+
+	Stats counts the Gets answered from the cache (hits), those that started a
+	computation (misses) and those that waited for one already started (dedups),
+	with the time spent computing.
+*/
+
+// Memo is a concurrency-safe memoization of a function of keys of type K
+type Memo[K comparable, V any] struct {
+	f      func(ctx context.Context, key K) (V, error)
+	hash   func(K) uint64
+	shards []shard[K, V]
+}
+
+// shard of a Memo, with its own counts so that no two shards write the same memory
+type shard[K comparable, V any] struct {
+	mu    sync.Mutex
+	cache map[K]*genEntry[V]
+	stats Stats
+	_     [64]byte // keeps shards on different cache lines
+}
+
+type genEntry[V any] struct {
+	value   V
+	err     error
+	ready   chan struct{}
+	waiters int
+	cancel  context.CancelFunc
+}
+
+// Stats of a Memo
+type Stats struct {
+	Hits, Misses, Dedups uint64
+	Computes             uint64 // computations finished
+	ComputeTime          time.Duration
+}
+
+// MeanCompute returns the mean time of a computation
+func (s Stats) MeanCompute() time.Duration {
+	if s.Computes == 0 {
+		return 0
+	}
+	return s.ComputeTime / time.Duration(s.Computes)
+}
+
+func (s Stats) String() string {
+	return fmt.Sprintf("%d hits, %d misses, %d dedups, %d computes of %s on average",
+		s.Hits, s.Misses, s.Dedups, s.Computes, s.MeanCompute())
+}
+
+// NewMemo returns a Memo of f with n shards, at least one. A nil hash uses the
+// default one.
+func NewMemo[K comparable, V any](f func(ctx context.Context, key K) (V, error), n int, hash func(K) uint64) *Memo[K, V] {
+	if n < 1 {
+		n = 1
+	}
+	if hash == nil {
+		seed := maphash.MakeSeed()
+		hash = func(key K) uint64 { return hashKey(seed, key) }
+	}
+	memo := &Memo[K, V]{f: f, hash: hash, shards: make([]shard[K, V], n)}
+	for i := range memo.shards {
+		memo.shards[i].cache = make(map[K]*genEntry[V])
+	}
+	return memo
+}
+
+// hashKey is the default hash of a key
+func hashKey[K comparable](seed maphash.Seed, key K) uint64 {
+	switch k := any(key).(type) {
+	case string:
+		return maphash.String(seed, k)
+	case int:
+		return mix(uint64(k))
+	case int64:
+		return mix(uint64(k))
+	case uint64:
+		return mix(k)
+	}
+	return maphash.String(seed, fmt.Sprintf("%#v", key))
+}
+
+// mix spreads the bits of an integer key, as the finalizer of splitmix64
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	return x ^ x>>31
+}
+
+func (memo *Memo[K, V]) shardOf(key K) *shard[K, V] {
+	return &memo.shards[memo.hash(key)%uint64(len(memo.shards))]
+}
+
+// Get method
+func (memo *Memo[K, V]) Get(ctx context.Context, key K) (V, error) {
+	s := memo.shardOf(key)
+	s.mu.Lock()
+	e := s.cache[key]
+	switch {
+	case e == nil:
+		s.stats.Misses++
+		fctx, cancel := context.WithCancel(context.Background())
+		e = &genEntry[V]{ready: make(chan struct{}), cancel: cancel}
+		s.cache[key] = e
+		go memo.call(fctx, s, e, key)
+	case isReady(e.ready):
+		s.stats.Hits++
+		s.mu.Unlock()
+		return e.value, e.err
+	default:
+		s.stats.Dedups++
+	}
+	e.waiters++
+	s.mu.Unlock()
+
+	select {
+	case <-e.ready:
+		return e.value, e.err
+	case <-ctx.Done():
+		s.mu.Lock()
+		e.waiters--
+		if e.waiters == 0 && !isReady(e.ready) {
+			e.cancel()
+			if s.cache[key] == e {
+				delete(s.cache, key)
+			}
+		}
+		s.mu.Unlock()
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+func (memo *Memo[K, V]) call(ctx context.Context, s *shard[K, V], e *genEntry[V], key K) {
+	start := time.Now()
+	e.value, e.err = memo.f(ctx, key)
+	s.mu.Lock()
+	s.stats.Computes++
+	s.stats.ComputeTime += time.Since(start)
+	if (ctx.Err() != nil || isCancellation(e.err)) && s.cache[key] == e {
+		delete(s.cache, key)
+	}
+	s.mu.Unlock()
+	e.cancel()
+	close(e.ready)
+}
+
+// Stats returns the counts of all shards
+func (memo *Memo[K, V]) Stats() Stats {
+	var total Stats
+	for i := range memo.shards {
+		s := &memo.shards[i]
+		s.mu.Lock()
+		total.Hits += s.stats.Hits
+		total.Misses += s.stats.Misses
+		total.Dedups += s.stats.Dedups
+		total.Computes += s.stats.Computes
+		total.ComputeTime += s.stats.ComputeTime
+		s.mu.Unlock()
+	}
+	return total
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMemoGeneric(t *testing.T) {
+	var calls int32
+	memo := NewMemo(func(ctx context.Context, n int) (string, error) {
+		atomic.AddInt32(&calls, 1)
+		if n < 0 {
+			return "", errors.New("negative")
+		}
+		time.Sleep(time.Millisecond)
+		return strconv.Itoa(n), nil
+	}, 8, nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			if s, err := memo.Get(context.Background(), n%10); err != nil || s != strconv.Itoa(n%10) {
+				t.Errorf("Get(%d) = %q, %v", n%10, s, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if _, err := memo.Get(context.Background(), -1); err == nil {
+		t.Errorf("Get(-1) did not fail")
+	}
+
+	stats := memo.Stats()
+	if calls != 11 || stats.Misses != 11 || stats.Computes != 11 {
+		t.Errorf("%d calls, stats %v, want 11 computations", calls, stats)
+	}
+	if stats.Hits+stats.Dedups != 90 || stats.MeanCompute() <= 0 {
+		t.Errorf("stats %v, want 90 hits and dedups", stats)
+	}
+}
+
+func TestMemoKeys(t *testing.T) {
+	type point struct{ X, Y int }
+	memo := NewMemo(func(ctx context.Context, p point) (int, error) {
+		return p.X * p.Y, nil
+	}, 4, nil)
+	for i := 0; i < 20; i++ {
+		if v, _ := memo.Get(context.Background(), point{i, 2}); v != 2*i {
+			t.Errorf("Get(%d, 2) = %d", i, v)
+		}
+	}
+	if s := memo.Stats(); s.Misses != 20 || s.Hits != 0 {
+		t.Errorf("stats %v", s)
+	}
+	hashed := 0
+	byX := NewMemo(func(ctx context.Context, p point) (int, error) { return p.X, nil }, 4,
+		func(p point) uint64 { hashed++; return uint64(p.X) })
+	byX.Get(context.Background(), point{1, 1})
+	if hashed != 1 {
+		t.Errorf("hash given to NewMemo not used")
+	}
+}
+
+// Benchmarks of warm caches, Gets spread over keys from parallel goroutines
+
+const benchKeys = 1024
+
+var keys = func() []string {
+	k := make([]string, benchKeys)
+	for i := range k {
+		k[i] = "https://example.com/" + strconv.Itoa(i)
+	}
+	return k
+}()
+
+func identity(key string) (interface{}, error) { return key, nil }
+
+func identityCtx(ctx context.Context, key string) (interface{}, error) { return key, nil }
+
+func benchmarkGet(b *testing.B, get func(key string)) {
+	for _, key := range keys {
+		get(key)
+	}
+	var seed int64
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := int(atomic.AddInt64(&seed, 7919))
+		for pb.Next() {
+			get(keys[i%benchKeys])
+			i++
+		}
+	})
+}
+
+func BenchmarkMemo2(b *testing.B) {
+	memo := New2(identity)
+	benchmarkGet(b, func(key string) { memo.Get(key) })
+}
+
+func BenchmarkMemo3(b *testing.B) {
+	memo := New3(identity)
+	benchmarkGet(b, func(key string) { memo.Get(key) })
+}
+
+func BenchmarkMemo4(b *testing.B) {
+	memo := New4(identityCtx)
+	benchmarkGet(b, func(key string) { memo.Get(context.Background(), key) })
+}
+
+func BenchmarkMemo5(b *testing.B) {
+	memo := New5(identityCtx)
+	defer memo.Close()
+	benchmarkGet(b, func(key string) { memo.Get(context.Background(), key) })
+}
+
+func BenchmarkMemo(b *testing.B) {
+	for _, n := range []int{1, 4, 16, 64} {
+		b.Run(strconv.Itoa(n)+"shards", func(b *testing.B) {
+			memo := NewMemo(func(ctx context.Context, key string) (string, error) { return key, nil }, n, nil)
+			benchmarkGet(b, func(key string) { memo.Get(context.Background(), key) })
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"crypto/sha256"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+)
+
+/*
+	The seen set of main is keyed by URL, so URLs naming the same page must be
+	written the same way. Normalize lowers the case of the scheme and the host,
+	drops the default port, the fragment and the dot segments of the path, the
+	trailing slash unless KeepTrailingSlash, and the query parameters in
+	StripParams (a name ending in * is a prefix), and sorts the remaining ones by
+	name.
+
+	A page naming another URL as canonical, in <link rel="canonical">, leads only
+	to that URL; its links are followed from there. A page with the same body as
+	one crawled before is not followed either. The bodies seen are kept in memory
+	only, not in the checkpoint, so after -resume a page is compared with those
+	crawled since.
+*/
+
+// trackingParams are the query parameters stripped by default
+var trackingParams = []string{"utm_*", "gclid", "fbclid", "msclkid", "yclid", "mc_cid", "mc_eid", "_ga"}
+
+// Normalizer struct
+type Normalizer struct {
+	KeepTrailingSlash bool
+	StripParams       []string
+}
+
+// normalizer gives the URLs of the crawl their canonical form, set up by main. It
+// keeps trailing slashes, so the mirror saves a directory page as its index.html.
+var normalizer = &Normalizer{KeepTrailingSlash: true, StripParams: trackingParams}
+
+// Normalize returns the canonical form of rawurl
+func (n *Normalizer) Normalize(rawurl string) (string, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return "", err
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	if port := u.Port(); port == "80" && u.Scheme == "http" || port == "443" && u.Scheme == "https" {
+		u.Host = strings.TrimSuffix(u.Host, ":"+port)
+	}
+	u.Fragment, u.RawFragment = "", ""
+
+	escaped := u.EscapedPath() // so that an escaped / stays one
+	if escaped == "" && u.Host != "" {
+		escaped = "/"
+	}
+	if escaped != "" && u.Opaque == "" {
+		trailing := strings.HasSuffix(escaped, "/")
+		escaped = path.Clean(escaped)
+		if trailing && n.KeepTrailingSlash && escaped != "/" {
+			escaped += "/"
+		}
+		if p, err := url.PathUnescape(escaped); err == nil {
+			u.Path, u.RawPath = p, escaped
+		}
+	}
+
+	u.ForceQuery = false
+	if u.RawQuery != "" {
+		query := u.Query()
+		for name := range query {
+			if n.strip(name) {
+				delete(query, name)
+			}
+		}
+		u.RawQuery = query.Encode() // sorted by name
+	}
+	return u.String(), nil
+}
+
+// strip reports whether the query parameter name is dropped
+func (n *Normalizer) strip(name string) bool {
+	name = strings.ToLower(name)
+	for _, s := range n.StripParams {
+		if s == "" {
+			continue
+		}
+		if prefix := strings.TrimSuffix(s, "*"); prefix != s {
+			if strings.HasPrefix(name, strings.ToLower(prefix)) {
+				return true
+			}
+		} else if name == strings.ToLower(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// contentIndex remembers the first URL of each body
+type contentIndex struct {
+	mu    sync.Mutex
+	first map[[sha256.Size]byte]string
+}
+
+// contents is the index of the bodies of the crawl
+var contents = &contentIndex{first: make(map[[sha256.Size]byte]string)}
+
+// duplicate returns the URL a body was first seen at, and whether that is not url
+func (c *contentIndex) duplicate(body []byte, url string) (string, bool) {
+	sum := sha256.Sum256(body)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	first, ok := c.first[sum]
+	if !ok {
+		c.first[sum] = url
+		return url, false
+	}
+	return first, first != url
+}
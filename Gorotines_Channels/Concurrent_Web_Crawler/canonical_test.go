@@ -0,0 +1,105 @@
+package main
+
+import (
+	"crypto/sha256"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	n := &Normalizer{StripParams: trackingParams}
+	for _, test := range []struct {
+		url, want string
+	}{
+		{"http://a/b", "http://a/b"},
+		{"http://a/b/", "http://a/b"},
+		{"HTTP://A/b#frag", "http://a/b"},
+		{"http://a", "http://a/"},
+		{"http://a/", "http://a/"},
+		{"http://a:80/b", "http://a/b"},
+		{"https://a:443/b", "https://a/b"},
+		{"http://a:443/b", "http://a:443/b"},
+		{"https://a:8443/b", "https://a:8443/b"},
+		{"http://a/b?b=1&a=2", "http://a/b?a=2&b=1"},
+		{"http://a/b?a=2&b=1", "http://a/b?a=2&b=1"},
+		{"http://a/b?", "http://a/b"},
+		{"http://a/b?utm_source=x&UTM_Medium=y&id=3&gclid=z", "http://a/b?id=3"},
+		{"http://a/b?utm_source=x", "http://a/b"},
+		{"http://a/x/./y/../z", "http://a/x/z"},
+		{"http://a/../b", "http://a/b"},
+		{"http://a/B", "http://a/B"}, // paths are case-sensitive
+		{"http://a/a%2Fb/", "http://a/a%2Fb"},
+		{"http://a/a%20b", "http://a/a%20b"},
+		{"mailto:a@b", "mailto:a@b"},
+	} {
+		got, err := n.Normalize(test.url)
+		if err != nil || got != test.want {
+			t.Errorf("Normalize(%s) = %s, %v, want %s", test.url, got, err, test.want)
+		}
+	}
+}
+
+func TestNormalizeOptions(t *testing.T) {
+	n := &Normalizer{KeepTrailingSlash: true, StripParams: []string{"session", "ref_*", ""}}
+	for _, test := range []struct {
+		url, want string
+	}{
+		{"http://a/b/", "http://a/b/"},
+		{"http://a/b/./", "http://a/b/"},
+		{"http://a/b", "http://a/b"},
+		{"http://a/?session=1&ref_src=2&ref=3&utm_source=4", "http://a/?ref=3&utm_source=4"},
+	} {
+		if got, _ := n.Normalize(test.url); got != test.want {
+			t.Errorf("Normalize(%s) = %s, want %s", test.url, got, test.want)
+		}
+	}
+	if _, err := n.Normalize("http://a b/"); err == nil {
+		t.Errorf("Normalize of a bad URL did not fail")
+	}
+}
+
+func TestCanonicalAndDuplicates(t *testing.T) {
+	pages := map[string]string{
+		"/a":     `<a href="/b/#top">b</a> <a href="/B?utm_source=feed&z=1&y=2">B</a>`,
+		"/copy":  `<a href="/b/#top">b</a> <a href="/B?utm_source=feed&z=1&y=2">B</a>`,
+		"/print": `<head><link rel="canonical" href="/a?utm_campaign=x"></head><a href="/c">c</a>`,
+		"/self":  `<head><link rel="Canonical" href="/self?utm_source=x"></head><a href="/c">c</a>`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+	defer func(c *contentIndex) { contents = c }(contents)
+	contents = &contentIndex{first: make(map[[sha256.Size]byte]string)}
+
+	for _, test := range []struct {
+		path string
+		want []string
+	}{
+		{"/a", []string{"/b/", "/B?y=2&z=1"}},
+		{"/copy", nil}, // the same body as /a
+		{"/a", []string{"/b/", "/B?y=2&z=1"}},
+		{"/print", []string{"/a"}},
+		{"/self", []string{"/c"}},
+	} {
+		works, err := limitCrawl(Work{depht: 1, url: server.URL + test.path})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, w := range works {
+			got = append(got, strings.TrimPrefix(w.url, server.URL))
+		}
+		if strings.Join(got, " ") != strings.Join(test.want, " ") {
+			t.Errorf("links of %s: %v, want %v", test.path, got, test.want)
+		}
+	}
+}
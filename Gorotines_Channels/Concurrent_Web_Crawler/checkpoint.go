@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+/*
+	The state of a crawl is kept in an append-only log, a line of JSON for each
+	change of a Work: found, crawled, failed. The last line of a URL is its state,
+	so resuming reads the whole log, takes every URL as seen and the pending ones
+	as the frontier, and writes the log again with only those last lines.
+
+	Lines are buffered and flushed every few seconds and on exit, so a crash loses
+	at most the last few; a line cut short by one is skipped.
+
+	A new crawl does not start over a log holding another one, which only
+	-resume or -overwrite can go on from or discard.
+*/
+
+// Status of a Work
+type Status int
+
+// Statuses
+const (
+	Pending Status = iota // found, not crawled yet
+	Done
+	Failed // after the last attempt, or disallowed
+)
+
+var statusNames = [...]string{Pending: "pending", Done: "done", Failed: "failed"}
+
+func (s Status) String() string {
+	if s < 0 || int(s) >= len(statusNames) {
+		return fmt.Sprintf("Status(%d)", int(s))
+	}
+	return statusNames[s]
+}
+
+// MarshalText method
+func (s Status) MarshalText() ([]byte, error) { return []byte(s.String()), nil }
+
+// UnmarshalText method
+func (s *Status) UnmarshalText(text []byte) error {
+	for i, name := range statusNames {
+		if string(text) == name {
+			*s = Status(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown status %q", text)
+}
+
+// record is a line of the log
+type record struct {
+	URL      string `json:"url"`
+	Depth    int    `json:"depth"`
+	Status   Status `json:"status"`
+	Attempts int    `json:"attempts,omitempty"`
+}
+
+type checkpoint struct {
+	file *os.File
+	w    *bufio.Writer
+	enc  *json.Encoder
+}
+
+// createCheckpoint starts an empty log. A log with a crawl in it is kept, unless
+// overwrite.
+func createCheckpoint(name string, overwrite bool) (*checkpoint, error) {
+	if fi, err := os.Stat(name); err == nil && fi.Size() > 0 && !overwrite {
+		return nil, fmt.Errorf("%s holds a crawl: go on with -resume, or start over with -overwrite", name)
+	}
+	file, err := os.Create(name)
+	if err != nil {
+		return nil, err
+	}
+	return newCheckpoint(file), nil
+}
+
+func newCheckpoint(file *os.File) *checkpoint {
+	w := bufio.NewWriter(file)
+	return &checkpoint{file: file, w: w, enc: json.NewEncoder(w)}
+}
+
+// resumeCheckpoint reads the log, compacts it and opens it to go on. It returns
+// the works in the order they were found.
+func resumeCheckpoint(name string) (*checkpoint, []*Work, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	var works []*Work
+	byURL := make(map[string]*Work)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		var r record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			continue // cut short by a crash
+		}
+		w := byURL[r.URL]
+		if w == nil {
+			w = &Work{url: r.URL}
+			byURL[r.URL] = w
+			works = append(works, w)
+		}
+		w.depht, w.status, w.attempts = r.Depth, r.Status, r.Attempts
+	}
+	file.Close()
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("reading %s: %v", name, err)
+	}
+
+	// the compacted log replaces the old one only once it is complete
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".checkpoint-*")
+	if err != nil {
+		return nil, nil, err
+	}
+	c := newCheckpoint(tmp)
+	for _, w := range works {
+		c.record(w)
+	}
+	if err := c.Flush(); err != nil {
+		c.Close()
+		os.Remove(tmp.Name())
+		return nil, nil, err
+	}
+	if err := os.Rename(tmp.Name(), name); err != nil {
+		c.Close()
+		os.Remove(tmp.Name())
+		return nil, nil, err
+	}
+	return c, works, nil
+}
+
+// record appends the state of w
+func (c *checkpoint) record(w *Work) error {
+	return c.enc.Encode(record{URL: w.url, Depth: w.depht, Status: w.status, Attempts: w.attempts})
+}
+
+// Flush writes the buffered records to the file
+func (c *checkpoint) Flush() error {
+	if err := c.w.Flush(); err != nil {
+		return err
+	}
+	return c.file.Sync()
+}
+
+// Close flushes and closes the log
+func (c *checkpoint) Close() error {
+	err := c.Flush()
+	if cerr := c.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var graph = map[string][]string{
+	"a": {"b", "c"},
+	"b": {"a", "d"},
+	"c": {"d", "e"},
+	"d": {},
+	"e": {"a"},
+}
+
+// fakeCrawl crawls graph, counting the crawls of each URL; those in block wait
+// for the channel to be closed
+type fakeCrawl struct {
+	mu    sync.Mutex
+	calls map[string]int
+	fail  map[string]error // the error of the first crawls
+	fails int              // how many crawls fail
+	block map[string]chan struct{}
+}
+
+func (c *fakeCrawl) crawl(work Work) ([]Work, error) {
+	c.mu.Lock()
+	if c.calls == nil {
+		c.calls = make(map[string]int)
+	}
+	c.calls[work.url]++
+	calls := c.calls[work.url]
+	block := c.block[work.url]
+	c.mu.Unlock()
+	if block != nil {
+		<-block
+	}
+	if err := c.fail[work.url]; err != nil && calls <= c.fails {
+		return nil, err
+	}
+	var found []Work
+	for _, link := range graph[work.url] {
+		found = append(found, Work{depht: work.depht + 1, url: link})
+	}
+	return found, nil
+}
+
+func newFrontier(t *testing.T, name string, crawl func(Work) ([]Work, error)) *frontier {
+	cp, err := createCheckpoint(name, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &frontier{crawl: crawl, checkpoint: cp, maxAttempts: 3, every: time.Hour}
+}
+
+func statuses(f *frontier) map[string]string {
+	got := make(map[string]string)
+	for url, w := range f.seen {
+		got[url] = w.status.String()
+	}
+	return got
+}
+
+func TestFrontier(t *testing.T) {
+	c := &fakeCrawl{}
+	f := newFrontier(t, filepath.Join(t.TempDir(), "crawl"), c.crawl)
+	if err := f.run([]*Work{{depht: 1, url: "a"}}, nil); err != nil {
+		t.Fatal(err)
+	}
+	f.checkpoint.Close()
+	for url := range graph {
+		if c.calls[url] != 1 || f.seen[url].status != Done {
+			t.Errorf("%s crawled %d times, %s", url, c.calls[url], f.seen[url].status)
+		}
+	}
+}
+
+func TestFrontierAttempts(t *testing.T) {
+	disallowed := errors.New("d: " + ErrDisallowed.Error())
+	for _, test := range []struct {
+		fails int
+		err   error
+		want  string
+		calls int
+	}{
+		{2, errors.New("timeout"), "done", 3},
+		{3, errors.New("timeout"), "failed", 3},
+		{1, errors.New("timeout"), "done", 2},
+		{1, ErrDisallowed, "failed", 1},
+		{1, disallowed, "done", 2}, // not wrapping ErrDisallowed
+	} {
+		c := &fakeCrawl{fail: map[string]error{"d": test.err}, fails: test.fails}
+		f := newFrontier(t, filepath.Join(t.TempDir(), "crawl"), c.crawl)
+		f.run([]*Work{{depht: 1, url: "a"}}, nil)
+		f.checkpoint.Close()
+		d := f.seen["d"]
+		if d.status.String() != test.want || c.calls["d"] != test.calls || d.attempts != test.calls {
+			t.Errorf("%d failures of %v: %s after %d calls and %d attempts, want %s after %d",
+				test.fails, test.err, d.status, c.calls["d"], d.attempts, test.want, test.calls)
+		}
+	}
+}
+
+// Each attempt waits twice as long as the one before it.
+func TestFrontierRetryAfter(t *testing.T) {
+	const retryAfter = 30 * time.Millisecond
+	var mu sync.Mutex
+	var robots []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			mu.Lock()
+			robots = append(robots, time.Now())
+			mu.Unlock()
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+	fetcher := NewFetcher(defaultAgent, 1, 0)
+	f := newFrontier(t, filepath.Join(t.TempDir(), "crawl"), func(work Work) ([]Work, error) {
+		return nil, get(fetcher, work.url)
+	})
+	f.retryAfter = retryAfter
+	if err := f.run([]*Work{{depht: 1, url: server.URL + "/a"}}, nil); err != nil {
+		t.Fatal(err)
+	}
+	f.checkpoint.Close()
+	if w := f.seen[server.URL+"/a"]; w.status != Failed || len(robots) != 3 {
+		t.Fatalf("%s after %d reads of robots.txt, want failed after 3", w.status, len(robots))
+	}
+	for i, want := range []time.Duration{retryAfter, 2 * retryAfter} {
+		if gap := robots[i+1].Sub(robots[i]); gap < want {
+			t.Errorf("attempt %d started %s after the one before, want at least %s", i+2, gap, want)
+		}
+	}
+}
+
+// A crawl that failed as robots.txt could not be read is tried again, against a
+// robots.txt fetched again.
+func TestFrontierRobotsUnavailable(t *testing.T) {
+	var robots int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" && atomic.AddInt32(&robots, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+	fetcher := NewFetcher(defaultAgent, 1, 0)
+	f := newFrontier(t, filepath.Join(t.TempDir(), "crawl"), func(work Work) ([]Work, error) {
+		return nil, get(fetcher, work.url)
+	})
+	if err := f.run([]*Work{{depht: 1, url: server.URL + "/a"}}, nil); err != nil {
+		t.Fatal(err)
+	}
+	f.checkpoint.Close()
+	if w := f.seen[server.URL+"/a"]; w.status != Done || w.attempts != 2 {
+		t.Errorf("%s after %d attempts, want done after 2", w.status, w.attempts)
+	}
+}
+
+func TestResume(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "crawl")
+	release := make(chan struct{})
+	defer close(release)
+	first := &fakeCrawl{block: map[string]chan struct{}{"c": release}}
+	f := newFrontier(t, name, first.crawl)
+	f.every = time.Millisecond
+
+	interrupt := make(chan os.Signal, 1)
+	go func() {
+		for { // until d is done, as the checkpoint shows, while c waits
+			data, _ := os.ReadFile(name)
+			if strings.Contains(string(data), `"url":"d","depth":3,"status":"done"`) {
+				interrupt <- os.Interrupt
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}()
+	if err := f.run([]*Work{{depht: 1, url: "a"}}, interrupt); err != errInterrupted {
+		t.Fatalf("run = %v, want interrupted", err)
+	}
+	if err := f.checkpoint.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// an unfinished line, as a crash would leave
+	file, _ := os.OpenFile(name, os.O_APPEND|os.O_WRONLY, 0)
+	file.WriteString(`{"url":"e","dep`)
+	file.Close()
+
+	cp, works, err := resumeCheckpoint(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pending []string
+	for _, w := range works {
+		if w.status == Pending {
+			pending = append(pending, w.url)
+		}
+	}
+	if strings.Join(pending, " ") != "c" {
+		t.Errorf("pending after the interrupt %v, want [c]", pending)
+	}
+
+	second := &fakeCrawl{}
+	f = &frontier{crawl: second.crawl, checkpoint: cp, maxAttempts: 3, every: time.Millisecond}
+	if err := f.run(works, nil); err != nil {
+		t.Fatal(err)
+	}
+	cp.Close()
+	if second.calls["c"] != 1 || second.calls["e"] != 1 || len(second.calls) != 2 {
+		t.Errorf("crawls after resuming %v, want c and e once", second.calls)
+	}
+	for url, status := range statuses(f) {
+		if status != "done" {
+			t.Errorf("%s %s after resuming", url, status)
+		}
+	}
+
+	// the log was compacted: one line for each URL before the resumed crawl
+	data, _ := os.ReadFile(name)
+	if lines := strings.Count(string(data), "\n"); lines != 4+3 {
+		t.Errorf("%d lines in the log, want 7:\n%s", lines, data)
+	}
+}
+
+func TestCreateCheckpointExisting(t *testing.T) {
+	dir := t.TempDir()
+	empty := filepath.Join(dir, "empty")
+	os.WriteFile(empty, nil, 0666)
+	if cp, err := createCheckpoint(empty, false); err != nil {
+		t.Errorf("createCheckpoint of an empty file: %v", err)
+	} else {
+		cp.Close()
+	}
+
+	name := filepath.Join(dir, "crawl")
+	crawl := `{"url":"a","depth":1,"status":"pending"}` + "\n"
+	os.WriteFile(name, []byte(crawl), 0666)
+	if _, err := createCheckpoint(name, false); err == nil || !strings.Contains(err.Error(), "-resume") {
+		t.Errorf("createCheckpoint over a crawl = %v, want an error naming -resume", err)
+	}
+	if data, _ := os.ReadFile(name); string(data) != crawl {
+		t.Errorf("the crawl was changed to %q", data)
+	}
+
+	cp, err := createCheckpoint(name, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cp.Close()
+	if data, _ := os.ReadFile(name); len(data) != 0 {
+		t.Errorf("overwritten log holds %q", data)
+	}
+}
+
+func TestStatusText(t *testing.T) {
+	for _, s := range []Status{Pending, Done, Failed} {
+		text, _ := s.MarshalText()
+		var got Status
+		if err := got.UnmarshalText(text); err != nil || got != s {
+			t.Errorf("%s read back as %s, %v", s, got, err)
+		}
+	}
+	var s Status
+	if err := s.UnmarshalText([]byte("lost")); err == nil {
+		t.Errorf("unknown status read")
+	}
+}
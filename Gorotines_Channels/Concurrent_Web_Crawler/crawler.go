@@ -18,14 +18,16 @@ package main
 */
 
 import (
+	"bytes"
+	"io/ioutil"
 	"strings"
 	"flag"
 	"fmt"
-	"io/ioutil"
 	"log"
 	"net/http"
-	"net/url"
 	"os"
+	"os/signal"
+	"time"
 	"golang.org/x/net/html"
 )
 
@@ -44,37 +46,90 @@ func forEachNode(n *html.Node, pre, post func(n *html.Node)) {
 
 // Extract function
 func Extract(url string) ([]string, error) {
-	resp, err := http.Get(url)
+	p, err := extractPage(url)
+	if err != nil {
+		return nil, err
+	}
+	return p.links, nil
+}
+
+// page is what extractPage finds in a page, with its URLs normalized
+type page struct {
+	links       []string
+	canonical   string // from <link rel="canonical">
+	duplicateOf string // the first URL with the same body
+}
+
+// extractPage fetches url and returns its links, its canonical URL and the page it
+// is a duplicate of. A duplicate is mirrored all the same, so that the links to its
+// URL in the other mirrored pages lead somewhere.
+func extractPage(url string) (*page, error) {
+	resp, err := fetcher.Get(url)
 	if err != nil {
 		return nil, err
 	}
 	if resp.StatusCode != http.StatusOK {
 		resp.Body.Close()
-		return nil, fmt.Errorf("getting %s as HTML: %v", url, err)
+		return nil, &StatusError{URL: url, Status: resp.Status, Code: resp.StatusCode}
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "" && !strings.HasPrefix(ct, "text/html") {
+		resp.Body.Close()
+		return &page{}, nil // no links
 	}
 
-	doc, err := html.Parse(resp.Body)
+	body, err := ioutil.ReadAll(resp.Body)
 	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %v", url, err)
+	}
+	doc, err := html.Parse(bytes.NewReader(body))
 	if err != nil {
 		return nil, fmt.Errorf("parsing %s as HTML: %v", url, err)
 	}
-	var links []string
+	p := &page{}
+	normalized := func(href string) string {
+		link, err := resp.Request.URL.Parse(href)
+		if err != nil {
+			return ""
+		}
+		n, err := normalizer.Normalize(link.String())
+		if err != nil {
+			return ""
+		}
+		return n
+	}
 	visitNode := func(n *html.Node) {
-		if n.Type == html.ElementNode && n.Data == "a" {
+		if n.Type != html.ElementNode {
+			return
+		}
+		switch {
+		case n.Data == "a":
 			for _, a := range n.Attr {
 				if a.Key != "href" {
 					continue
 				}
-				link, err := resp.Request.URL.Parse(a.Val)
-				if err != nil {
-					continue
+				if link := normalized(a.Val); link != "" {
+					p.links = append(p.links, link)
+				}
+			}
+		case n.Data == "link" && p.canonical == "":
+			for _, rel := range strings.Fields(strings.ToLower(attr(n, "rel"))) {
+				if rel == "canonical" {
+					p.canonical = normalized(attr(n, "href"))
 				}
-				links = append(links, link.String())
 			}
 		}
 	}
 	forEachNode(doc, visitNode, nil)
-	return links, nil
+	if mirror != nil {
+		if err := mirror.SavePage(resp, doc); err != nil {
+			log.Print(err)
+		}
+	}
+	if first, dup := contents.duplicate(body, url); dup {
+		p.duplicateOf = first
+	}
+	return p, nil
 }
 
 // crawl1
@@ -108,91 +163,95 @@ func crawl2(url string) []string {
 var (
 	maxdepth int
 	args     []string
+	concurrency, perHost int
+	delay    time.Duration
+	agent    string
+	checkpointFile string
+	resume   bool
+	overwrite bool
+	mirroring bool
+	reportFile string
+	stripParams string
+	keepSlash bool
+	every    time.Duration
+	attempts int
+	retryAfter time.Duration
 )
 var idchannel = make(chan struct{}, 2)
 
+// fetcher makes the requests of Extract, set up by main from the flags
+var fetcher = NewFetcher(defaultAgent, 2, 0)
+
 // Work struct
 type Work struct {
 	depht int
 	url   string
+	status Status
+	attempts int // crawls tried
 }
 
 func init() {
 	flag.IntVar(&maxdepth, "depth", 3, "max depth to crawl")
-	flag.Parse()
-	args = flag.Args()
-	
+	flag.IntVar(&concurrency, "concurrency", 2, "max requests at a time")
+	flag.IntVar(&perHost, "host-concurrency", 2, "max requests at a time to a host")
+	flag.DurationVar(&delay, "delay", 500*time.Millisecond, "least time between requests to a host, more if robots.txt asks")
+	flag.StringVar(&agent, "agent", defaultAgent, "User-Agent, its product token is matched against robots.txt")
+	flag.StringVar(&checkpointFile, "checkpoint", "crawl.checkpoint", "file keeping the state of the crawl")
+	flag.BoolVar(&resume, "resume", false, "go on with the crawl in the checkpoint")
+	flag.BoolVar(&overwrite, "overwrite", false, "start over, discarding the crawl in the checkpoint")
+	flag.DurationVar(&every, "every", 5*time.Second, "time between writes of the checkpoint")
+	flag.IntVar(&attempts, "attempts", 3, "crawls of a URL before giving up")
+	flag.DurationVar(&retryAfter, "retry-after", time.Second, "wait before the second crawl of a URL, doubled for each next")
+	flag.BoolVar(&mirroring, "mirror", false, "save the pages of the starting hosts under "+samedir)
+	flag.StringVar(&stripParams, "strip", strings.Join(trackingParams, ","), "query parameters dropped from URLs, a * at the end matches any suffix")
+	flag.BoolVar(&keepSlash, "keep-slash", true, "keep the trailing slash of URLs, false to take /a/ and /a as one page")
+	flag.StringVar(&reportFile, "report", "", "check links, writing a report to `file`, as JSON if it ends in .json and CSV otherwise")
 }
 
-func limitCrawl(work Work) []Work {
+func limitCrawl(work Work) ([]Work, error) {
 	fmt.Printf("%s %d\n", work.url, work.depht)
-	if work.depht >= maxdepth {
-		return nil
+	last := work.depht >= maxdepth
+	if last && report == nil {
+		return nil, nil
 	}
 	idchannel <- struct{}{}
-	list, err := Extract(work.url)
+	p, err := extractPage(work.url)
 	<-idchannel
 	if err != nil {
-		log.Print(err)
+		return nil, err
+	}
+	if report != nil && (last || !report.Follows(work.url)) {
+		return nil, nil // checked only
+	}
+	if p.duplicateOf != "" {
+		log.Printf("%s: same content as %s", work.url, p.duplicateOf)
+		return nil, nil
+	}
+	list := p.links
+	if p.canonical != "" && p.canonical != work.url {
+		list = []string{p.canonical} // its links are followed from there
+	}
+	if report != nil {
+		for _, link := range list {
+			report.Refer(work.url, link)
+		}
 	}
 	works := []Work{}
 	for _, link := range list {
-		works = append(works, Work{work.depht + 1, link})
+		works = append(works, Work{depht: work.depht + 1, url: link})
 	}
-	return works
+	return works, nil
 }
 
 // Ejercicio 8.7 (Custom)
 
 const samedir = "./mirror"
 
-func savaDoc(data []byte, name string ) {
-	name = strings.Replace(name,"https://","",1)
-	name = strings.Replace(name,".com","",1)
-	name = strings.ReplaceAll(name,"/","")
-	name = strings.ReplaceAll(name,"#","")
-	dirname := samedir + "/" + name
-	if _,err := os.Stat(dirname); os.IsNotExist(err) {
-		os.Mkdir(dirname,os.ModePerm)
-		file, er := os.Create(dirname + "/" + name + ".html")
-		if er != nil {log.Fatal(er)}
-		_,err := file.Write(data)
-		if err != nil { log.Printf("Error in save file %s\n", err)}
-		defer file.Close()
-	}
-}
-
-func sameDomain(x, y string) bool {
-	urlx, errx := url.Parse(x)
-	urly, erry := url.Parse(y)
-
-	if errx != nil && erry != nil {
-		return false
-	}
-
-	if urlx.Host != urly.Host {
-		return false
-	}
-
-	return true
-}
-
-func contructor(ws chan []Work) {
-	links := <-ws
-	domain := args[0]
-	for _, link := range links {
-		same := sameDomain(domain, link.url)
-		if same {
-			resp, err := http.Get(link.url)
-			if err == nil && resp.StatusCode == 200 {
-				data, _ := ioutil.ReadAll(resp.Body)
-				savaDoc(data,link.url)
-			}
-			defer resp.Body.Close()
-		}
+// mirror saves the pages Extract gets, when not nil
+var mirror *Mirror
 
-	}
-}
+// report records the requests and links of the crawl, when not nil
+var report *Report
 
 func main() {
 	/*
@@ -260,33 +319,83 @@ func main() {
 
 
 	*/
-	if _, err := os.Stat(samedir); os.IsNotExist(err) {
-		os.Mkdir(samedir,os.ModePerm)
-	}
-	workers := make(chan []Work)
-	var n int
-	n++
-	go func() {
-		works := []Work{}
-		for _, elm := range args {
-			works = append(works, Work{1, elm})
+	flag.Parse()
+	args = flag.Args()
+	idchannel = make(chan struct{}, concurrency)
+	fetcher = NewFetcher(agent, perHost, delay)
+	normalizer = &Normalizer{KeepTrailingSlash: keepSlash, StripParams: strings.Split(stripParams, ",")}
+	for i, arg := range args {
+		if n, err := normalizer.Normalize(arg); err == nil {
+			args[i] = n
 		}
-		workers <- works
-	}()
-
-	seen := make(map[string]bool)
-	for ; n > 0; n-- {
-		list := <-workers
-		for _, link := range list {
-			if !seen[link.url] {
-				seen[link.url] = true
-				n++
-				go func(link Work) {
-					workers <- limitCrawl(link)
-				    contructor(workers)
-				}(link)
+	}
 
+	var cp *checkpoint
+	var works []*Work
+	var err error
+	if resume {
+		cp, works, err = resumeCheckpoint(checkpointFile)
+		if err == nil && len(args) == 0 {
+			for _, w := range works {
+				if w.depht == 1 {
+					args = append(args, w.url)
+				}
 			}
 		}
+	} else {
+		cp, err = createCheckpoint(checkpointFile, overwrite)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(args) == 0 {
+		log.Fatal("no URL to crawl")
+	}
+	if mirroring {
+		mirror = NewMirror(samedir, fetcher, args)
+	}
+	if reportFile != "" {
+		report = NewReport(args)
+		fetcher.Observe = report.Observe
+	}
+	known := make(map[string]bool)
+	for _, w := range works {
+		known[w.url] = true
+	}
+	for _, elm := range args {
+		if !known[elm] {
+			w := &Work{depht: 1, url: elm}
+			works = append(works, w)
+			cp.record(w)
+		}
+	}
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	f := &frontier{
+		crawl:       limitCrawl,
+		checkpoint:  cp,
+		maxAttempts: attempts,
+		retryAfter:  retryAfter,
+		every:       every,
+	}
+	err = f.run(works, interrupt)
+	if cerr := cp.Close(); err == nil {
+		err = cerr
+	}
+	counts := f.count()
+	log.Printf("%d done, %d failed, %d pending", counts[Done], counts[Failed], counts[Pending])
+	if report != nil {
+		if err := report.Save(reportFile); err != nil {
+			log.Print(err)
+		}
+		report.WriteSummary(os.Stdout)
+	}
+	if err == errInterrupted {
+		log.Printf("state kept in %s, go on with -resume", checkpointFile)
+		os.Exit(130)
+	}
+	if err != nil {
+		log.Fatal(err)
 	}
 }
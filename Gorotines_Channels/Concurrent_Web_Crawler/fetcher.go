@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+/*
+	Fetcher makes the requests of the crawler politely. It sends its User-Agent,
+	skips the URLs robots.txt disallows, and limits each host on its own: at most
+	PerHost requests at a time, the same counting semaphore as tokens but one per
+	host, with their starts at least the delay apart, the larger of Delay and the
+	Crawl-delay of the site. The global limits, tokens and idchannel, still apply.
+
+	A request holds its host's token until the body of the response is closed.
+	Every redirect it follows waits for the delay of the host redirected to.
+	Observe, when set, is told of every request, with the time to its response.
+*/
+
+// ErrDisallowed is the error for URLs robots.txt does not allow
+var ErrDisallowed = errors.New("disallowed by robots.txt")
+
+const defaultAgent = "gopl-crawler/1.0"
+
+// Fetcher struct
+type Fetcher struct {
+	UserAgent string
+	Delay     time.Duration // least time between requests to a host
+	Observe   func(url string, resp *http.Response, err error, elapsed time.Duration)
+	client    *http.Client
+	robots    *robotsCache
+	hosts     *hostLimiter
+}
+
+// NewFetcher function
+func NewFetcher(agent string, perHost int, delay time.Duration) *Fetcher {
+	f := &Fetcher{UserAgent: agent, Delay: delay, hosts: newHostLimiter(perHost)}
+	f.robots = newRobotsCache(&http.Client{Timeout: 30 * time.Second}, agent)
+	f.robots.limit = func(host string) func() { return f.hosts.acquire(host, f.Delay) }
+	f.client = &http.Client{
+		Timeout: 30 * time.Second,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			// the request redirected holds a token of its host
+			held := req.URL.Host == via[0].URL.Host
+			rules := f.robots.rules(req.Context(), req.URL, held)
+			if !rules.Allowed(robotsPath(req.URL)) {
+				return fmt.Errorf("redirect to %s: %w", req.URL, ErrDisallowed)
+			}
+			f.hosts.wait(req.URL.Host, f.delay(rules))
+			return nil
+		},
+	}
+	return f
+}
+
+// Allowed reports whether robots.txt lets the crawler fetch u
+func (f *Fetcher) Allowed(ctx context.Context, u *url.URL) bool {
+	return f.robots.rules(ctx, u, false).Allowed(robotsPath(u))
+}
+
+// robotsPath returns the path and query of u, as robots.txt rules match them
+func robotsPath(u *url.URL) string {
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	return path
+}
+
+// Get method
+func (f *Fetcher) Get(rawurl string) (*http.Response, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	ctx := context.Background()
+	rules := f.robots.rules(ctx, u, false)
+	if !rules.Allowed(robotsPath(u)) {
+		err := fmt.Errorf("%s: %w", rawurl, ErrDisallowed)
+		if rules.err != nil { // the site can not be reached
+			err = fmt.Errorf("%s: %w", rawurl, rules.err)
+		}
+		f.observe(rawurl, nil, err, 0)
+		return nil, err
+	}
+	release := f.hosts.acquire(u.Host, f.delay(rules))
+
+	req, err := http.NewRequestWithContext(ctx, "GET", rawurl, nil)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	req.Header.Set("User-Agent", f.UserAgent)
+	start := time.Now()
+	resp, err := f.client.Do(req)
+	if err != nil {
+		resp = nil // with a redirect refused
+	}
+	f.observe(rawurl, resp, err, time.Since(start))
+	if err != nil {
+		release()
+		return nil, err
+	}
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+// delay returns the least time between requests to a host with rules
+func (f *Fetcher) delay(rules *Rules) time.Duration {
+	if rules.Delay < f.Delay {
+		return f.Delay
+	}
+	return rules.Delay
+}
+
+func (f *Fetcher) observe(url string, resp *http.Response, err error, elapsed time.Duration) {
+	if f.Observe != nil {
+		f.Observe(url, resp, err, elapsed)
+	}
+}
+
+// releasingBody gives back the token of its host when closed
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+	once    sync.Once
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
+
+// hostLimiter limits the requests to each host
+type hostLimiter struct {
+	perHost int
+	mu      sync.Mutex
+	hosts   map[string]*hostSlot
+}
+
+type hostSlot struct {
+	tokens chan struct{}
+	next   time.Time // earliest start of the next request, guarded by the mutex
+}
+
+func newHostLimiter(perHost int) *hostLimiter {
+	if perHost < 1 {
+		perHost = 1
+	}
+	return &hostLimiter{perHost: perHost, hosts: make(map[string]*hostSlot)}
+}
+
+// acquire waits for a token of host and for delay after the previous request
+// started, and returns the function giving the token back
+func (l *hostLimiter) acquire(host string, delay time.Duration) (release func()) {
+	slot := l.slot(host)
+	slot.tokens <- struct{}{}
+	l.wait(host, delay)
+	return func() { <-slot.tokens }
+}
+
+// wait waits for delay after the previous request to host started, without a
+// token, for the redirects of a request that holds one
+func (l *hostLimiter) wait(host string, delay time.Duration) {
+	slot := l.slot(host)
+	l.mu.Lock()
+	start := time.Now()
+	if slot.next.After(start) {
+		start = slot.next
+	}
+	slot.next = start.Add(delay)
+	l.mu.Unlock()
+	time.Sleep(time.Until(start))
+}
+
+func (l *hostLimiter) slot(host string) *hostSlot {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	slot := l.hosts[host]
+	if slot == nil {
+		slot = &hostSlot{tokens: make(chan struct{}, l.perHost)}
+		l.hosts[host] = slot
+	}
+	return slot
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// site serves robots and counts the other requests, the most at a time and the
+// User-Agent headers seen
+type site struct {
+	robots     string
+	status     int // of robots.txt, 200 by default
+	latency    time.Duration
+	mu         sync.Mutex
+	requests   int
+	active     int32
+	maxActive  int32
+	starts     []time.Time
+	userAgents map[string]bool
+}
+
+func (s *site) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/robots.txt" {
+		if s.status != 0 {
+			w.WriteHeader(s.status)
+		}
+		io.WriteString(w, s.robots)
+		return
+	}
+	if r.URL.Path == "/redirect" {
+		http.Redirect(w, r, "/private/x", http.StatusFound)
+		return
+	}
+	active := atomic.AddInt32(&s.active, 1)
+	s.mu.Lock()
+	s.requests++
+	s.starts = append(s.starts, time.Now())
+	if s.userAgents == nil {
+		s.userAgents = make(map[string]bool)
+	}
+	s.userAgents[r.UserAgent()] = true
+	if active > s.maxActive {
+		s.maxActive = active
+	}
+	s.mu.Unlock()
+	time.Sleep(s.latency)
+	atomic.AddInt32(&s.active, -1)
+	io.WriteString(w, "<html></html>")
+}
+
+func get(f *Fetcher, url string) error {
+	resp, err := f.Get(url)
+	if err != nil {
+		return err
+	}
+	io.Copy(io.Discard, resp.Body)
+	return resp.Body.Close()
+}
+
+func TestFetcherRobots(t *testing.T) {
+	s := &site{robots: "User-agent: *\nDisallow: /private/\n"}
+	server := httptest.NewServer(s)
+	defer server.Close()
+	f := NewFetcher("testbot/2.0", 2, 0)
+
+	for _, test := range []struct {
+		path       string
+		disallowed bool
+	}{
+		{"/", false},
+		{"/public/a", false},
+		{"/private/a", true},
+		{"/redirect", true}, // to a disallowed page
+	} {
+		err := get(f, server.URL+test.path)
+		if errors.Is(err, ErrDisallowed) != test.disallowed {
+			t.Errorf("Get(%s) = %v, disallowed should be %v", test.path, err, test.disallowed)
+		}
+	}
+	if s.requests != 2 {
+		t.Errorf("%d pages requested, want 2", s.requests)
+	}
+	if !s.userAgents["testbot/2.0"] || len(s.userAgents) != 1 {
+		t.Errorf("User-Agent headers %v, want testbot/2.0", s.userAgents)
+	}
+}
+
+func TestFetcherRobotsStatus(t *testing.T) {
+	for _, test := range []struct {
+		status  int
+		allowed bool
+	}{
+		{http.StatusNotFound, true},
+		{http.StatusForbidden, true},
+		{http.StatusServiceUnavailable, false},
+	} {
+		s := &site{robots: "User-agent: *\nDisallow: /\n", status: test.status}
+		server := httptest.NewServer(s)
+		err := get(NewFetcher(defaultAgent, 1, 0), server.URL+"/a")
+		server.Close()
+		if (err == nil) != test.allowed {
+			t.Errorf("robots.txt %d: Get = %v, want allowed %v", test.status, err, test.allowed)
+		}
+	}
+}
+
+func TestFetcherRobotsRetry(t *testing.T) {
+	var robots int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&robots, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		io.WriteString(w, "User-agent: *\nDisallow: /private/\n")
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	f := NewFetcher(defaultAgent, 1, 0)
+
+	err := get(f, server.URL+"/a")
+	if err == nil || errors.Is(err, ErrDisallowed) {
+		t.Errorf("Get with robots.txt unavailable = %v", err)
+	}
+	// the failure was not kept: robots.txt is fetched again, once
+	if err := get(f, server.URL+"/a"); err != nil {
+		t.Errorf("Get after robots.txt came back = %v", err)
+	}
+	if err := get(f, server.URL+"/private/a"); !errors.Is(err, ErrDisallowed) {
+		t.Errorf("Get(/private/a) = %v, want disallowed", err)
+	}
+	if n := atomic.LoadInt32(&robots); n != 2 {
+		t.Errorf("robots.txt fetched %d times, want 2", n)
+	}
+}
+
+func TestFetcherRobotsDelay(t *testing.T) {
+	const delay = 40 * time.Millisecond
+	var mu sync.Mutex
+	var starts []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		starts = append(starts, time.Now())
+		mu.Unlock()
+	}))
+	defer server.Close()
+	f := NewFetcher(defaultAgent, 1, delay)
+
+	if err := get(f, server.URL+"/a"); err != nil {
+		t.Fatal(err)
+	}
+	// robots.txt, then the page, delay apart as any two requests
+	if len(starts) != 2 {
+		t.Fatalf("%d requests, want 2", len(starts))
+	}
+	if gap := starts[1].Sub(starts[0]); gap < delay-5*time.Millisecond {
+		t.Errorf("page requested %s after robots.txt, want at least %s", gap, delay)
+	}
+}
+
+func TestFetcherPerHostLimits(t *testing.T) {
+	s := &site{latency: 20 * time.Millisecond}
+	server := httptest.NewServer(s)
+	defer server.Close()
+	f := NewFetcher(defaultAgent, 2, 0)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := get(f, server.URL+"/"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if s.maxActive > 2 {
+		t.Errorf("%d requests at a time to one host, want at most 2", s.maxActive)
+	}
+}
+
+func TestFetcherDelay(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		robots string
+		delay  time.Duration
+		want   time.Duration
+	}{
+		{"flag", "", 30 * time.Millisecond, 30 * time.Millisecond},
+		{"crawl-delay", "User-agent: *\nCrawl-delay: 0.05\n", 10 * time.Millisecond, 50 * time.Millisecond},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			s := &site{robots: test.robots}
+			server := httptest.NewServer(s)
+			defer server.Close()
+			f := NewFetcher(defaultAgent, 4, test.delay)
+
+			var wg sync.WaitGroup
+			for i := 0; i < 4; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					get(f, server.URL+"/")
+				}()
+			}
+			wg.Wait()
+			for i := 1; i < len(s.starts); i++ {
+				// the server sees a request a little after it was sent
+				if gap := s.starts[i].Sub(s.starts[i-1]); gap < test.want-5*time.Millisecond {
+					t.Errorf("requests %s apart, want at least %s", gap, test.want)
+				}
+			}
+		})
+	}
+}
+
+func TestFetcherRedirectDelay(t *testing.T) {
+	const delay = 40 * time.Millisecond
+	var mu sync.Mutex
+	var starts []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		starts = append(starts, time.Now())
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/a":
+			http.Redirect(w, r, "/b", http.StatusFound)
+		case "/b":
+			http.Redirect(w, r, "/c", http.StatusFound)
+		}
+	}))
+	defer server.Close()
+	f := NewFetcher(defaultAgent, 1, delay)
+
+	if err := get(f, server.URL+"/a"); err != nil {
+		t.Fatal(err)
+	}
+	// robots.txt, /a, /b and /c, delay apart as any two requests
+	if len(starts) != 4 {
+		t.Fatalf("%d requests, want 4", len(starts))
+	}
+	for i := 1; i < len(starts); i++ {
+		if gap := starts[i].Sub(starts[i-1]); gap < delay-5*time.Millisecond {
+			t.Errorf("request %d sent %s after the one before, want at least %s", i, gap, delay)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"os"
+	"time"
+)
+
+/*
+	The loop of Ejercicio 8.6, with the seen set and the frontier in Works that
+	carry their status and attempts, every change written to the checkpoint. A
+	failed crawl is tried again until maxAttempts, except when it can not
+	succeed: robots.txt disallows it, or the server answered with a 4xx status.
+	A robots.txt that could not be read is no reason to give up, as it is fetched
+	again for the next attempt. Each attempt waits twice as long as the one
+	before, from retryAfter, to give a site that is down time to recover. The links found by a crawl are recorded before the
+	crawl is marked done, so a crash between the two loses nothing.
+
+	On interrupt the loop stops at once; the crawls in flight are still pending in
+	the checkpoint, and are started again on resume.
+*/
+
+var errInterrupted = errors.New("interrupted")
+
+type crawlResult struct {
+	work  *Work
+	found []Work
+	err   error
+}
+
+type frontier struct {
+	crawl       func(Work) ([]Work, error)
+	checkpoint  *checkpoint
+	maxAttempts int
+	retryAfter  time.Duration // before the second attempt, doubled for each next
+	every       time.Duration // between flushes of the checkpoint
+	seen        map[string]*Work
+}
+
+// run crawls the pending works and what they lead to, until none is left
+func (f *frontier) run(works []*Work, interrupt <-chan os.Signal) error {
+	results := make(chan crawlResult)
+	quit := make(chan struct{})
+	var n int
+	start := func(w *Work, wait time.Duration) {
+		n++
+		go func(work Work) {
+			if wait > 0 {
+				select {
+				case <-time.After(wait):
+				case <-quit:
+					return
+				}
+			}
+			found, err := f.crawl(work)
+			select {
+			case results <- crawlResult{w, found, err}:
+			case <-quit:
+			}
+		}(*w)
+	}
+	f.seen = make(map[string]*Work)
+	for _, w := range works {
+		f.seen[w.url] = w
+		if w.status == Pending {
+			start(w, 0)
+		}
+	}
+
+	ticker := time.NewTicker(f.every)
+	defer ticker.Stop()
+	for n > 0 {
+		select {
+		case r := <-results:
+			n--
+			f.done(r, start)
+		case <-ticker.C:
+			if err := f.checkpoint.Flush(); err != nil {
+				close(quit)
+				return err
+			}
+		case <-interrupt:
+			close(quit)
+			return errInterrupted
+		}
+	}
+	return nil
+}
+
+// done records the result of a crawl, starting its links or a new attempt
+func (f *frontier) done(r crawlResult, start func(*Work, time.Duration)) {
+	w := r.work
+	w.attempts++
+	if r.err != nil {
+		log.Printf("%v (attempt %d)", r.err, w.attempts)
+		if w.attempts < f.maxAttempts && !permanent(r.err) {
+			f.checkpoint.record(w)
+			start(w, f.retryAfter<<(w.attempts-1))
+			return
+		}
+		w.status = Failed
+		f.checkpoint.record(w)
+		return
+	}
+	for _, link := range r.found {
+		if f.seen[link.url] == nil {
+			found := &Work{depht: link.depht, url: link.url}
+			f.seen[link.url] = found
+			f.checkpoint.record(found)
+			start(found, 0)
+		}
+	}
+	w.status = Done
+	f.checkpoint.record(w)
+}
+
+// count returns the number of works seen with each status
+func (f *frontier) count() map[Status]int {
+	counts := make(map[Status]int)
+	for _, w := range f.seen {
+		counts[w.status]++
+	}
+	return counts
+}
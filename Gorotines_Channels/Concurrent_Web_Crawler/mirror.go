@@ -0,0 +1,266 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/html"
+)
+
+/*
+	Ejercicio 8.7: a local mirror of the pages crawled on the hosts of the starting
+	URLs, one file per URL under <root>/<host>/ with the path of the URL (a port
+	goes after an _ in the name of the host):
+
+	http://host/              host/index.html
+	http://host/docs/         host/docs/index.html
+	http://host/docs/intro    host/docs/intro.html
+	http://host/a.php?x=1     host/a.php@x=1.html
+	http://host/css/s.css?v=2 host/css/s@v=2.css
+	http://host/doc.pdf       host/doc.pdf
+
+	Pages with no extension, or one of a server script, get .html so they open
+	offline; other files keep their name. A query goes in the name, after an @.
+	Only HTML responses are saved. URLs are normalized first, as the crawler does,
+	so the links to a page and the file saved for it agree.
+
+	The images, scripts, style sheets and media of a page on the same host are
+	downloaded with it, and the links to the same host in the saved copy are
+	rewritten relative to it, so the copy can be browsed offline. Links to other
+	hosts are made absolute. Links inside style sheets are left as they are.
+*/
+
+// Mirror struct
+type Mirror struct {
+	Root    string
+	hosts   map[string]bool
+	fetcher *Fetcher
+	mu      sync.Mutex
+	assets  map[string]bool // URLs downloaded, or being downloaded
+}
+
+// NewMirror returns a mirror of the hosts of roots
+func NewMirror(root string, fetcher *Fetcher, roots []string) *Mirror {
+	m := &Mirror{Root: root, hosts: make(map[string]bool), fetcher: fetcher, assets: make(map[string]bool)}
+	for _, r := range roots {
+		if u, err := url.Parse(r); err == nil {
+			m.hosts[u.Host] = true
+		}
+	}
+	return m
+}
+
+// Includes reports whether u is on a mirrored host
+func (m *Mirror) Includes(u *url.URL) bool {
+	return (u.Scheme == "http" || u.Scheme == "https") && m.hosts[u.Host]
+}
+
+// linkAttrs are the attributes with links, and whether they are of assets
+var linkAttrs = map[string]struct {
+	attr  string
+	asset bool
+}{
+	"a":      {"href", false},
+	"area":   {"href", false},
+	"iframe": {"src", false},
+	"img":    {"src", true},
+	"script": {"src", true},
+	"source": {"src", true},
+	"video":  {"src", true},
+	"audio":  {"src", true},
+	"embed":  {"src", true},
+	"link":   {"href", false}, // an asset for some rels
+}
+
+// SavePage saves the page of resp, parsed as doc, and its assets. The page is
+// saved as the URL requested, so links to it find it after a redirect; its links
+// are relative to the URL of the last request.
+func (m *Mirror) SavePage(resp *http.Response, doc *html.Node) error {
+	requested := resp.Request
+	for requested.Response != nil {
+		requested = requested.Response.Request
+	}
+	ct := resp.Header.Get("Content-Type")
+	if !m.Includes(canonicalURL(requested.URL)) || ct != "" && !strings.HasPrefix(ct, "text/html") {
+		return nil
+	}
+	base := resp.Request.URL
+	file := localPath(canonicalURL(requested.URL), true)
+
+	forEachNode(doc, func(n *html.Node) {
+		if n.Type != html.ElementNode {
+			return
+		}
+		la, ok := linkAttrs[n.Data]
+		if !ok {
+			return
+		}
+		asset := la.asset
+		if n.Data == "link" {
+			rel := strings.ToLower(attr(n, "rel"))
+			asset = strings.Contains(rel, "stylesheet") || strings.Contains(rel, "icon")
+		}
+		for i, a := range n.Attr {
+			if a.Key != la.attr || a.Val == "" || strings.HasPrefix(a.Val, "#") {
+				continue
+			}
+			link, err := base.Parse(a.Val)
+			if err != nil {
+				continue
+			}
+			fragment := link.Fragment
+			link = canonicalURL(link)
+			if !m.Includes(link) {
+				if link.Scheme == "http" || link.Scheme == "https" {
+					n.Attr[i].Val = link.String()
+				}
+				continue
+			}
+			target := localPath(link, !asset)
+			if asset {
+				m.saveAsset(link, target)
+			}
+			n.Attr[i].Val = relativeLink(file, target, fragment)
+		}
+	}, nil)
+
+	var b bytes.Buffer
+	if err := html.Render(&b, doc); err != nil {
+		return err
+	}
+	return m.write(file, b.Bytes())
+}
+
+// canonicalURL returns u as the normalizer of the crawl writes it
+func canonicalURL(u *url.URL) *url.URL {
+	s, err := normalizer.Normalize(u.String())
+	if err != nil {
+		return u
+	}
+	n, err := url.Parse(s)
+	if err != nil {
+		return u
+	}
+	return n
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// saveAsset downloads u once
+func (m *Mirror) saveAsset(u *url.URL, file string) {
+	whole := *u
+	whole.Fragment, whole.RawFragment = "", ""
+	key := whole.String()
+	m.mu.Lock()
+	saved := m.assets[key]
+	m.assets[key] = true
+	m.mu.Unlock()
+	if saved {
+		return
+	}
+	resp, err := m.fetcher.Get(key)
+	if err != nil {
+		log.Print(err)
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		log.Printf("getting %s: %s", key, resp.Status)
+		return
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err == nil {
+		err = m.write(file, data)
+	}
+	if err != nil {
+		log.Print(err)
+	}
+}
+
+// write saves data as file, a slash-separated path under the root
+func (m *Mirror) write(file string, data []byte) error {
+	name := filepath.Join(m.Root, filepath.FromSlash(file))
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(name, data, 0644)
+}
+
+// scriptExts are the extensions of pages made by the server
+var scriptExts = map[string]bool{"": true, ".php": true, ".asp": true, ".aspx": true, ".jsp": true, ".cgi": true}
+
+// localPath returns the slash-separated file of u in the mirror
+func localPath(u *url.URL, page bool) string {
+	p := u.Path
+	if p == "" || strings.HasSuffix(p, "/") {
+		p += "index.html"
+	}
+	dir, name := path.Split(path.Clean("/" + p))
+	ext := path.Ext(name)
+	if page && scriptExts[strings.ToLower(ext)] {
+		name, ext = name+".html", ".html"
+	}
+	if u.RawQuery != "" {
+		name = strings.TrimSuffix(name, ext) + "@" + u.RawQuery + ext
+	}
+	host := strings.ReplaceAll(u.Host, ":", "_")
+	segments := strings.Split(strings.Trim(dir, "/"), "/")
+	var b strings.Builder
+	b.WriteString(safeName(host))
+	for _, s := range append(segments, name) {
+		if s != "" {
+			b.WriteString("/")
+			b.WriteString(safeName(s))
+		}
+	}
+	return b.String()
+}
+
+// safeName replaces the characters some file systems do not allow in names
+func safeName(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r < ' ' || strings.ContainsRune(`<>:"/\|?*`, r) {
+			return '_'
+		}
+		return r
+	}, s)
+}
+
+// relativeLink returns the link to the file to from the file from, both in the
+// mirror, escaped for an attribute
+func relativeLink(from, to, fragment string) string {
+	fromDir := strings.Split(from, "/")
+	fromDir = fromDir[:len(fromDir)-1]
+	toParts := strings.Split(to, "/")
+	common := 0
+	for common < len(fromDir) && common < len(toParts)-1 && fromDir[common] == toParts[common] {
+		common++
+	}
+	var parts []string
+	for range fromDir[common:] {
+		parts = append(parts, "..")
+	}
+	for _, p := range toParts[common:] {
+		parts = append(parts, url.PathEscape(p))
+	}
+	link := strings.Join(parts, "/")
+	if fragment != "" {
+		link += "#" + url.PathEscape(fragment)
+	}
+	return link
+}
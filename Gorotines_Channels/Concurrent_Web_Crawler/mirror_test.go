@@ -0,0 +1,127 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocalPath(t *testing.T) {
+	for _, test := range []struct {
+		url  string
+		page bool
+		want string
+	}{
+		{"http://host", true, "host/index.html"},
+		{"http://host/", true, "host/index.html"},
+		{"http://host/docs/", true, "host/docs/index.html"},
+		{"http://host/docs/intro", true, "host/docs/intro.html"},
+		{"http://host/docs/intro.html", true, "host/docs/intro.html"},
+		{"http://host/a.php?x=1&y=2", true, "host/a.php@x=1&y=2.html"},
+		{"http://host/?page=2", true, "host/index@page=2.html"},
+		{"http://host/doc.pdf", true, "host/doc.pdf"},
+		{"http://host/css/s.css?v=2", false, "host/css/s@v=2.css"},
+		{"http://host/img/logo", false, "host/img/logo"},
+		{"http://host/q?path=/etc/passwd", true, "host/q@path=_etc_passwd.html"},
+		{"http://host/a/../../b", true, "host/b.html"},
+		{"http://host/a%20b/c:d", true, "host/a b/c_d.html"},
+		{"http://host:8080/x#frag", true, "host_8080/x.html"},
+	} {
+		u, _ := url.Parse(test.url)
+		if got := localPath(u, test.page); got != test.want {
+			t.Errorf("localPath(%s, %v) = %s, want %s", test.url, test.page, got, test.want)
+		}
+	}
+}
+
+func TestRelativeLink(t *testing.T) {
+	for _, test := range []struct {
+		from, to, fragment, want string
+	}{
+		{"h/index.html", "h/about.html", "", "about.html"},
+		{"h/index.html", "h/docs/a.html", "top", "docs/a.html#top"},
+		{"h/docs/a.html", "h/index.html", "", "../index.html"},
+		{"h/docs/a.html", "h/img/b c.png", "", "../img/b%20c.png"},
+		{"h/docs/x/a.html", "h/docs/y/b.html", "", "../y/b.html"},
+		{"h/a.html", "h/a.html", "", "a.html"},
+	} {
+		if got := relativeLink(test.from, test.to, test.fragment); got != test.want {
+			t.Errorf("relativeLink(%s, %s) = %s, want %s", test.from, test.to, got, test.want)
+		}
+	}
+}
+
+func TestMirror(t *testing.T) {
+	pages := map[string]string{
+		"/": `<html><head><link rel="stylesheet" href="/css/s.css?v=2"></head><body>
+<a href="/docs/">docs</a> <a href="http://elsewhere.example/x">out</a>
+<a href="#top">top</a> <a href="mailto:a@b">mail</a></body></html>`,
+		"/docs/": `<a href="../">home</a> <a href="intro#part">intro</a> <img src="/img/logo.png">
+<img src="../img/logo.png#again">`,
+		"/css/s.css":    "body { color: red }",
+		"/img/logo.png": "PNG",
+	}
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/old" {
+			http.Redirect(w, r, "/docs/", http.StatusMovedPermanently)
+			return
+		}
+		requests = append(requests, r.URL.Path)
+		body, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if strings.HasSuffix(r.URL.Path, "/") {
+			w.Header().Set("Content-Type", "text/html")
+		}
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	root := t.TempDir()
+	host := strings.ReplaceAll(strings.TrimPrefix(server.URL, "http://"), ":", "_")
+	mirror = NewMirror(root, NewFetcher(defaultAgent, 1, 0), []string{server.URL})
+	defer func() { mirror = nil }()
+	for _, page := range []string{"/", "/docs/", "/old"} {
+		if _, err := Extract(server.URL + page); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for file, want := range map[string][]string{
+		"index.html": {
+			`href="css/s@v=2.css"`, `href="docs/index.html"`, `href="http://elsewhere.example/x"`,
+			`href="#top"`, `href="mailto:a@b"`,
+		},
+		"docs/index.html": {`href="../index.html"`, `href="intro.html#part"`, `src="../img/logo.png"`, `src="../img/logo.png#again"`},
+		"old.html":        {`href="index.html"`, `href="docs/intro.html#part"`, `src="img/logo.png"`},
+		"css/s@v=2.css":   {"color: red"},
+		"img/logo.png":    {"PNG"},
+	} {
+		data, err := os.ReadFile(filepath.Join(root, host, filepath.FromSlash(file)))
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		for _, w := range want {
+			if !strings.Contains(string(data), w) {
+				t.Errorf("%s has no %s:\n%s", file, w, data)
+			}
+		}
+	}
+	logos := 0
+	for _, r := range requests {
+		if r == "/img/logo.png" {
+			logos++
+		}
+	}
+	if logos != 1 {
+		t.Errorf("logo downloaded %d times, want once", logos)
+	}
+}
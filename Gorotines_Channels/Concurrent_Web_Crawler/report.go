@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+	Report mode makes the crawler a broken-link checker. Every request of the
+	fetcher is recorded as a Visit: the status, the redirects followed, the content
+	type, the time to the response and the pages linking to the URL. Pages on the
+	hosts of the starting URLs are crawled; links to other hosts, and those at the
+	last depth, are only checked.
+
+	The report is written as CSV or JSON, with a summary of the broken links (4xx,
+	5xx and unreachable) by the page linking to them. A resumed crawl reports only
+	the URLs visited since it was resumed.
+*/
+
+// StatusError is the error of a response other than 200 OK
+type StatusError struct {
+	URL    string
+	Status string
+	Code   int
+}
+
+func (e *StatusError) Error() string { return fmt.Sprintf("getting %s: %s", e.URL, e.Status) }
+
+// permanent reports whether another attempt would fail as err did
+func permanent(err error) bool {
+	var se *StatusError
+	return errors.Is(err, ErrDisallowed) || errors.As(err, &se) && se.Code >= 400 && se.Code < 500
+}
+
+// Visit of a URL
+type Visit struct {
+	URL         string   `json:"url"`
+	Status      int      `json:"status,omitempty"` // zero when there was no response
+	Redirects   []string `json:"redirects,omitempty"`
+	ContentType string   `json:"content_type,omitempty"`
+	Millis      float64  `json:"response_ms"`
+	Error       string   `json:"error,omitempty"`
+	Referrers   []string `json:"referrers,omitempty"`
+	disallowed  bool
+}
+
+// Broken reports whether the link to v is broken: an error status, or no
+// response at all other than because robots.txt disallows it
+func (v *Visit) Broken() bool {
+	return v.Status >= 400 || v.Status == 0 && v.Error != "" && !v.disallowed
+}
+
+// Report struct
+type Report struct {
+	hosts  map[string]bool
+	mu     sync.Mutex
+	visits map[string]*Visit
+}
+
+// NewReport returns a report crawling the hosts of roots
+func NewReport(roots []string) *Report {
+	r := &Report{hosts: make(map[string]bool), visits: make(map[string]*Visit)}
+	for _, root := range roots {
+		if u, err := url.Parse(root); err == nil {
+			r.hosts[u.Host] = true
+		}
+	}
+	return r
+}
+
+// Follows reports whether the links of the page at rawurl are to be crawled
+func (r *Report) Follows(rawurl string) bool {
+	u, err := url.Parse(rawurl)
+	return err == nil && r.hosts[u.Host]
+}
+
+func (r *Report) visit(rawurl string) *Visit {
+	v := r.visits[rawurl]
+	if v == nil {
+		v = &Visit{URL: rawurl}
+		r.visits[rawurl] = v
+	}
+	return v
+}
+
+// Observe records a request of the fetcher, as its Observe hook
+func (r *Report) Observe(rawurl string, resp *http.Response, err error, elapsed time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	v := r.visit(rawurl)
+	v.Status, v.Redirects, v.ContentType, v.Error, v.disallowed = 0, nil, "", "", false
+	v.Millis = float64(elapsed.Microseconds()) / 1000
+	if err != nil {
+		v.Error = err.Error()
+		v.disallowed = errors.Is(err, ErrDisallowed)
+		return
+	}
+	v.Status = resp.StatusCode
+	v.ContentType = resp.Header.Get("Content-Type")
+	var chain []string
+	for req := resp.Request; req.Response != nil; req = req.Response.Request {
+		chain = append(chain, fmt.Sprintf("%d %s", req.Response.StatusCode, req.URL))
+	}
+	for i := len(chain) - 1; i >= 0; i-- {
+		v.Redirects = append(v.Redirects, chain[i])
+	}
+}
+
+// Refer records a link from the page at from to the URL to
+func (r *Report) Refer(from, to string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	v := r.visit(to)
+	for _, ref := range v.Referrers {
+		if ref == from {
+			return
+		}
+	}
+	v.Referrers = append(v.Referrers, from)
+}
+
+// Visits returns the URLs visited, sorted
+func (r *Report) Visits() []Visit {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var visits []Visit
+	for _, v := range r.visits {
+		if v.Status != 0 || v.Error != "" { // not only linked to
+			visits = append(visits, *v)
+		}
+	}
+	sort.Slice(visits, func(i, j int) bool { return visits[i].URL < visits[j].URL })
+	return visits
+}
+
+// WriteCSV writes a line for each visit
+func (r *Report) WriteCSV(out io.Writer) error {
+	w := csv.NewWriter(out)
+	w.Write([]string{"url", "status", "content_type", "response_ms", "redirects", "referrers", "error"})
+	for _, v := range r.Visits() {
+		w.Write([]string{
+			v.URL, strconv.Itoa(v.Status), v.ContentType, strconv.FormatFloat(v.Millis, 'f', 3, 64),
+			strings.Join(v.Redirects, " "), strings.Join(v.Referrers, " "), v.Error,
+		})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// WriteJSON writes the visits as a JSON array
+func (r *Report) WriteJSON(out io.Writer) error {
+	visits := r.Visits()
+	if visits == nil {
+		visits = []Visit{}
+	}
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(visits)
+}
+
+// Save writes the report to a file, as JSON if its name ends in .json and as CSV
+// otherwise
+func (r *Report) Save(name string) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if strings.HasSuffix(strings.ToLower(name), ".json") {
+		err = r.WriteJSON(f)
+	} else {
+		err = r.WriteCSV(f)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// WriteSummary writes the broken links under each page linking to them
+func (r *Report) WriteSummary(w io.Writer) error {
+	out := bufio.NewWriter(w)
+	visits := r.Visits()
+	byPage := make(map[string][]Visit)
+	var broken, status4xx, status5xx, unreachable int
+	for _, v := range visits {
+		if !v.Broken() {
+			continue
+		}
+		broken++
+		switch {
+		case v.Status >= 500:
+			status5xx++
+		case v.Status >= 400:
+			status4xx++
+		default:
+			unreachable++
+		}
+		referrers := v.Referrers
+		if len(referrers) == 0 {
+			referrers = []string{"(starting URL)"}
+		}
+		for _, ref := range referrers {
+			byPage[ref] = append(byPage[ref], v)
+		}
+	}
+	fmt.Fprintf(out, "%d URLs visited, %d broken: %d 4xx, %d 5xx, %d unreachable\n",
+		len(visits), broken, status4xx, status5xx, unreachable)
+	pages := make([]string, 0, len(byPage))
+	for page := range byPage {
+		pages = append(pages, page)
+	}
+	sort.Strings(pages)
+	for _, page := range pages {
+		fmt.Fprintf(out, "%s\n", page)
+		for _, v := range byPage[page] {
+			if v.Status != 0 {
+				fmt.Fprintf(out, "\t%d %s\n", v.Status, v.URL)
+			} else {
+				fmt.Fprintf(out, "\tunreachable %s: %s\n", v.URL, v.Error)
+			}
+		}
+	}
+	return out.Flush()
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReport(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<a href="/ok">ok</a> <a href="/missing">missing</a> <a href="/broken">broken</a>
+<a href="/moved">moved</a> <a href="/private/x">private</a> <a href="/logo.png">logo</a>
+<a href="http://127.0.0.1:1/down">down</a>`))
+	})
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<a href="/missing">again</a> <a href="/ok/deeper">deeper</a>`))
+	})
+	mux.HandleFunc("/broken", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "oops", http.StatusInternalServerError)
+	})
+	mux.Handle("/moved", http.RedirectHandler("/logo.png", http.StatusMovedPermanently))
+	mux.HandleFunc("/logo.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte("PNG"))
+	})
+	mux.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("User-agent: *\nDisallow: /private/\n"))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	defer func(depth int, f *Fetcher, c *contentIndex) {
+		maxdepth, fetcher, report, contents = depth, f, nil, c
+	}(maxdepth, fetcher, contents)
+	contents = &contentIndex{first: make(map[[sha256.Size]byte]string)}
+	maxdepth = 3
+	fetcher = NewFetcher(defaultAgent, 2, 0)
+	report = NewReport([]string{server.URL})
+	fetcher.Observe = report.Observe
+	cp, err := createCheckpoint(filepath.Join(t.TempDir(), "crawl"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &frontier{crawl: limitCrawl, checkpoint: cp, maxAttempts: 2, every: time.Hour}
+	if err := f.run([]*Work{{depht: 1, url: server.URL + "/"}}, nil); err != nil {
+		t.Fatal(err)
+	}
+	cp.Close()
+
+	visits := make(map[string]Visit)
+	for _, v := range report.Visits() {
+		visits[strings.TrimPrefix(v.URL, server.URL)] = v
+	}
+	for _, test := range []struct {
+		path, contentType string
+		status            int
+		broken            bool
+		referrers         int
+	}{
+		{"/", "text/html", 200, false, 0},
+		{"/ok", "text/html", 200, false, 1},
+		{"/ok/deeper", "text/plain; charset=utf-8", 404, true, 1}, // checked, at the last depth
+		{"/missing", "text/plain; charset=utf-8", 404, true, 2},
+		{"/broken", "text/plain; charset=utf-8", 500, true, 1},
+		{"/moved", "image/png", 200, false, 1},
+		{"/private/x", "", 0, false, 1},
+		{"/logo.png", "image/png", 200, false, 1},
+		{"http://127.0.0.1:1/down", "", 0, true, 1},
+	} {
+		v, ok := visits[test.path]
+		if !ok {
+			t.Errorf("%s not visited", test.path)
+			continue
+		}
+		if v.Status != test.status || v.ContentType != test.contentType || v.Broken() != test.broken ||
+			len(v.Referrers) != test.referrers {
+			t.Errorf("%s: status %d, %q, broken %v, referrers %v", test.path, v.Status, v.ContentType, v.Broken(), v.Referrers)
+		}
+	}
+	if moved := visits["/moved"]; len(moved.Redirects) != 1 || moved.Redirects[0] != "301 "+server.URL+"/logo.png" {
+		t.Errorf("redirects of /moved: %q", moved.Redirects)
+	}
+	if len(visits) != 9 {
+		t.Errorf("%d URLs visited, want 9", len(visits))
+	}
+
+	var summary bytes.Buffer
+	report.WriteSummary(&summary)
+	want := []string{
+		"9 URLs visited, 4 broken: 2 4xx, 1 5xx, 1 unreachable\n",
+		"\n" + server.URL + "/\n",
+		"\t500 " + server.URL + "/broken\n",
+		"\tunreachable http://127.0.0.1:1/down: ",
+		"\n" + server.URL + "/ok\n\t404 " + server.URL + "/missing\n\t404 " + server.URL + "/ok/deeper\n",
+	}
+	for _, w := range want {
+		if !strings.Contains(summary.String(), w) {
+			t.Errorf("summary has no %q:\n%s", w, summary.String())
+		}
+	}
+
+	dir := t.TempDir()
+	if err := report.Save(filepath.Join(dir, "links.json")); err != nil {
+		t.Fatal(err)
+	}
+	var decoded []Visit
+	data, _ := os.ReadFile(filepath.Join(dir, "links.json"))
+	if err := json.Unmarshal(data, &decoded); err != nil || len(decoded) != 9 {
+		t.Errorf("JSON report of %d visits, %v", len(decoded), err)
+	}
+	if err := report.Save(filepath.Join(dir, "links.csv")); err != nil {
+		t.Fatal(err)
+	}
+	data, _ = os.ReadFile(filepath.Join(dir, "links.csv"))
+	if lines := strings.Count(string(data), "\n"); lines != 10 || !strings.HasPrefix(string(data), "url,status,") {
+		t.Errorf("CSV report of %d lines:\n%s", lines, data)
+	}
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+	robots.txt, as RFC 9309 has it. The file is a list of groups, each of one or more
+	User-agent lines followed by Allow and Disallow rules; a crawler obeys the groups
+	naming its product token, or else those for "*". A path is allowed when the
+	longest rule matching it is an Allow, or no rule matches. Rules may use * for
+	any characters and end with $ to match the end of the path. Crawl-delay is not
+	in the RFC but widely used: the seconds to wait between requests.
+
+	The rules of each site are fetched once, when the first of its URLs is crawled,
+	and callers asking meanwhile wait for them as in the memos of chapter 9. The
+	request waits for a token of its host, as those of the pages do. A robots.txt
+	that is missing (4xx) allows everything; one that can not be read (5xx or a
+	network error) disallows everything, with the error kept as the reason, but
+	only for the callers of that fetch: it is not cached, so the next URL of the
+	site fetches robots.txt again.
+*/
+
+// Rules of a robots.txt for one user agent
+type Rules struct {
+	rules []rule
+	Delay time.Duration // Crawl-delay
+	err   error         // why robots.txt could not be fetched
+}
+
+type rule struct {
+	allow   bool
+	pattern string
+}
+
+var (
+	allowAll    = &Rules{}
+	disallowAll = &Rules{rules: []rule{{false, "/"}}}
+)
+
+// ParseRobots returns the rules of a robots.txt for the product token agent
+func ParseRobots(r io.Reader, agent string) *Rules {
+	agent = strings.ToLower(agent)
+	var mine, any Rules
+	var matched, anyMatched bool
+	var group []string // user agents of the current group
+	inRules := false
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		colon := strings.IndexByte(line, ':')
+		if colon < 0 {
+			continue
+		}
+		key := strings.ToLower(strings.TrimSpace(line[:colon]))
+		value := strings.TrimSpace(line[colon+1:])
+		if key == "user-agent" {
+			if inRules {
+				group, inRules = nil, false
+			}
+			group = append(group, strings.ToLower(value))
+			continue
+		}
+		if len(group) == 0 {
+			continue // a rule outside of any group
+		}
+		inRules = true
+		for _, ua := range group {
+			var rules *Rules
+			switch ua {
+			case agent:
+				rules, matched = &mine, true
+			case "*":
+				rules, anyMatched = &any, true
+			default:
+				continue
+			}
+			switch key {
+			case "allow", "disallow":
+				if value != "" {
+					rules.rules = append(rules.rules, rule{key == "allow", value})
+				}
+			case "crawl-delay":
+				if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+					rules.Delay = time.Duration(seconds * float64(time.Second))
+				}
+			}
+		}
+	}
+	switch {
+	case matched:
+		return &mine
+	case anyMatched:
+		return &any
+	}
+	return allowAll
+}
+
+// Allowed reports whether the path of a URL, with its query, may be crawled
+func (r *Rules) Allowed(path string) bool {
+	if path == "/robots.txt" {
+		return true
+	}
+	best, allowed := -1, true
+	for _, rule := range r.rules {
+		if !matchRule(rule.pattern, path) {
+			continue
+		}
+		if n := len(rule.pattern); n > best || n == best && rule.allow {
+			best, allowed = n, rule.allow
+		}
+	}
+	return allowed
+}
+
+// matchRule reports whether path matches a pattern with * and a final $
+func matchRule(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	if anchored {
+		pattern = pattern[:len(pattern)-1]
+	}
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	rest := path[len(parts[0]):]
+	for i, part := range parts[1:] {
+		if anchored && i == len(parts)-2 {
+			return strings.HasSuffix(rest, part)
+		}
+		j := strings.Index(rest, part)
+		if j < 0 {
+			return false
+		}
+		rest = rest[j+len(part):]
+	}
+	return !anchored || rest == ""
+}
+
+// robotsCache fetches and keeps the rules of each site
+type robotsCache struct {
+	client *http.Client
+	agent  string // User-Agent header
+	token  string // product token, matched against User-agent lines
+	mu     sync.Mutex
+	sites  map[string]*robotsEntry
+
+	limit func(host string) (release func()) // waits for a token of host, if set
+}
+
+type robotsEntry struct {
+	rules *Rules
+	ready chan struct{}
+}
+
+func newRobotsCache(client *http.Client, agent string) *robotsCache {
+	token := agent
+	if i := strings.IndexAny(token, "/ "); i >= 0 {
+		token = token[:i]
+	}
+	return &robotsCache{client: client, agent: agent, token: token, sites: make(map[string]*robotsEntry)}
+}
+
+// rules returns the rules of the site of u. The request for robots.txt does not
+// wait for a token of the host when the caller holds one already, held.
+func (c *robotsCache) rules(ctx context.Context, u *url.URL, held bool) *Rules {
+	site := u.Scheme + "://" + u.Host
+	c.mu.Lock()
+	e := c.sites[site]
+	if e == nil {
+		e = &robotsEntry{ready: make(chan struct{})}
+		c.sites[site] = e
+		c.mu.Unlock()
+		if c.limit != nil && !held {
+			release := c.limit(u.Host)
+			e.rules = c.fetch(ctx, site)
+			release()
+		} else {
+			e.rules = c.fetch(ctx, site)
+		}
+		if e.rules.err != nil {
+			c.mu.Lock()
+			delete(c.sites, site)
+			c.mu.Unlock()
+		}
+		close(e.ready)
+	} else {
+		c.mu.Unlock()
+		<-e.ready
+	}
+	return e.rules
+}
+
+func (c *robotsCache) fetch(ctx context.Context, site string) *Rules {
+	req, err := http.NewRequestWithContext(ctx, "GET", site+"/robots.txt", nil)
+	if err != nil {
+		return disallowAll
+	}
+	req.Header.Set("User-Agent", c.agent)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return &Rules{rules: disallowAll.rules, err: err}
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return ParseRobots(io.LimitReader(resp.Body, 500<<10), c.token)
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		return allowAll
+	}
+	return &Rules{rules: disallowAll.rules, err: fmt.Errorf("%s/robots.txt: %s", site, resp.Status)}
+}
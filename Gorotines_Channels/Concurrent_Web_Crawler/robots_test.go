@@ -0,0 +1,95 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const robotsTxt = `
+# comments and blank lines are ignored
+User-agent: *
+Disallow: /private/
+Allow: /private/open
+Crawl-delay: 2
+
+User-agent: OtherBot
+User-agent: gopl-crawler
+Disallow: /*.pdf$
+Disallow: /tmp
+Allow: /tmp/keep
+Disallow: /search?*q=
+Crawl-delay: 0.5
+
+User-agent: gopl-crawler
+Disallow: /merged  # groups of the same agent are combined
+`
+
+func TestParseRobots(t *testing.T) {
+	for _, test := range []struct {
+		agent string
+		path  string
+		want  bool
+	}{
+		{"gopl-crawler", "/", true},
+		{"gopl-crawler", "/private/x", true}, // the * group does not apply
+		{"gopl-crawler", "/docs/a.pdf", false},
+		{"gopl-crawler", "/docs/a.pdf?x", true},
+		{"gopl-crawler", "/tmp/a", false},
+		{"gopl-crawler", "/tmpfile", false},
+		{"gopl-crawler", "/tmp/keep/a", true}, // the longer rule wins
+		{"gopl-crawler", "/search?lang=en&q=go", false},
+		{"gopl-crawler", "/search?lang=en", true},
+		{"gopl-crawler", "/merged/a", false},
+		{"GOPL-Crawler", "/tmp", false}, // agents are matched ignoring case
+		{"otherbot", "/merged", true},
+		{"somebot", "/private/x", false},
+		{"somebot", "/private/open/x", true},
+		{"somebot", "/tmp", true},
+		{"somebot", "/robots.txt", true},
+	} {
+		rules := ParseRobots(strings.NewReader(robotsTxt), test.agent)
+		if got := rules.Allowed(test.path); got != test.want {
+			t.Errorf("%s: Allowed(%s) = %v, want %v", test.agent, test.path, got, test.want)
+		}
+	}
+}
+
+func TestParseRobotsDelay(t *testing.T) {
+	for _, test := range []struct {
+		agent, robots string
+		want          time.Duration
+	}{
+		{"gopl-crawler", robotsTxt, 500 * time.Millisecond},
+		{"somebot", robotsTxt, 2 * time.Second},
+		{"somebot", "User-agent: gopl-crawler\nCrawl-delay: 1", 0},
+		{"somebot", "User-agent: *\nCrawl-delay: soon", 0},
+	} {
+		if got := ParseRobots(strings.NewReader(test.robots), test.agent).Delay; got != test.want {
+			t.Errorf("%s: delay %s, want %s", test.agent, got, test.want)
+		}
+	}
+}
+
+func TestParseRobotsEdgeCases(t *testing.T) {
+	for _, test := range []struct {
+		robots, path string
+		want         bool
+	}{
+		{"", "/a", true},
+		{"Disallow: /", "/a", true}, // outside of any group
+		{"User-agent: *\nDisallow:", "/a", true},
+		{"User-agent: *\nDisallow: /", "/a", false},
+		{"User-agent: *\nDisallow: /a\nAllow: /a", "/a", true}, // Allow wins a tie
+		{"user-agent: *\ndisallow: /A", "/a", true},            // paths are case-sensitive
+		{"User-agent: *\nDisallow: /*/edit$", "/x/y/edit", false},
+		{"User-agent: *\nDisallow: /*/edit$", "/x/edit/y", true},
+		{"User-agent: *\nDisallow: /a$", "/a", false},
+		{"User-agent: *\nDisallow: /a$", "/ab", true},
+	} {
+		rules := ParseRobots(strings.NewReader(test.robots), "gopl-crawler")
+		if got := rules.Allowed(test.path); got != test.want {
+			t.Errorf("%q: Allowed(%s) = %v, want %v", test.robots, test.path, got, test.want)
+		}
+	}
+}
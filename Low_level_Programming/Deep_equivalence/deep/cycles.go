@@ -0,0 +1,100 @@
+package deep
+
+import (
+	"fmt"
+	"reflect"
+	"unsafe"
+)
+
+/*
+	IsCyclic and FindCycles look for references that lead back to themselves, the
+	values on which Display, the JSON and S-expression encoders never stop. Pointers,
+	maps and slices are remembered by address and type, as equal does with
+	comparison, while they are being walked. Reaching one of them again closes a
+	cycle, reported as the paths of the references on it, root named v:
+
+	v            the list
+	v->.Next     its Next field, pointing back to v
+
+	Each reference is walked once, so the cost is linear in the size of the value.
+*/
+
+// reference is a pointer, map or slice that may lead back to itself
+type reference struct {
+	p unsafe.Pointer
+	t reflect.Type
+}
+
+type cycleFinder struct {
+	onPath map[reference]int // position in path of the references being walked
+	done   map[reference]bool
+	path   []string
+	cycles [][]string
+	first  bool // stop at the first cycle
+}
+
+// IsCyclic reports whether v holds a reference that leads back to itself
+func IsCyclic(v interface{}) bool {
+	f := newCycleFinder()
+	f.first = true
+	f.walk("v", reflect.ValueOf(v))
+	return len(f.cycles) > 0
+}
+
+// FindCycles returns the paths of the references forming each cycle of v
+func FindCycles(v interface{}) [][]string {
+	f := newCycleFinder()
+	f.walk("v", reflect.ValueOf(v))
+	return f.cycles
+}
+
+func newCycleFinder() *cycleFinder {
+	return &cycleFinder{onPath: make(map[reference]int), done: make(map[reference]bool)}
+}
+
+func (f *cycleFinder) walk(path string, v reflect.Value) {
+	if f.first && len(f.cycles) > 0 {
+		return
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice:
+		if v.IsNil() {
+			return
+		}
+		ref := reference{unsafe.Pointer(v.Pointer()), v.Type()}
+		if i, ok := f.onPath[ref]; ok {
+			cycle := append([]string(nil), f.path[i:]...)
+			f.cycles = append(f.cycles, append(cycle, path))
+			return
+		}
+		if f.done[ref] {
+			return
+		}
+		f.onPath[ref] = len(f.path)
+		f.path = append(f.path, path)
+		defer func() {
+			delete(f.onPath, ref)
+			f.path = f.path[:len(f.path)-1]
+			f.done[ref] = true
+		}()
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		f.walk(path+"->", v.Elem())
+	case reflect.Interface:
+		f.walk(path, v.Elem())
+	case reflect.Array, reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			f.walk(fmt.Sprintf("%s[%d]", path, i), v.Index(i))
+		}
+	case reflect.Struct:
+		for i, n := 0, v.NumField(); i < n; i++ {
+			f.walk(path+"."+v.Type().Field(i).Name, v.Field(i))
+		}
+	case reflect.Map:
+		for _, k := range sortedKeys(v, v) {
+			f.walk(fmt.Sprintf("%s[%#v]", path, k), v.MapIndex(k))
+		}
+	}
+}
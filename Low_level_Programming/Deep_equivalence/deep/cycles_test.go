@@ -0,0 +1,50 @@
+package deep
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestFindCycles(t *testing.T) {
+	type node struct {
+		Name     string
+		Next     *node
+		Children map[string]*node
+		Any      interface{}
+	}
+
+	list := &node{Name: "a", Next: &node{Name: "b"}}
+	list.Next.Next = list
+
+	tree := &node{Name: "root", Children: map[string]*node{"x": {Name: "x"}, "y": {Name: "y"}}}
+	tree.Children["y"].Any = tree.Children
+
+	self := []interface{}{1, nil}
+	self[1] = self
+
+	shared := &node{Name: "shared"}
+	dag := []*node{shared, shared, {Next: shared}}
+
+	tests := []struct {
+		name string
+		v    interface{}
+		want string
+	}{
+		{"nil", nil, "[]"},
+		{"acyclic", &node{Name: "a", Next: &node{Name: "b"}}, "[]"},
+		{"shared", dag, "[]"},
+		{"list", list, "[[v v->.Next v->.Next->.Next]]"},
+		{"map", tree, `[[v->.Children v->.Children["y"] v->.Children["y"]->.Any]]`},
+		{"slice", self, "[[v v[1]]]"},
+		{"value", *list, "[[v.Next v.Next->.Next v.Next->.Next->.Next]]"},
+	}
+	for _, test := range tests {
+		got := fmt.Sprint(FindCycles(test.v))
+		if got != test.want {
+			t.Errorf("FindCycles(%s) = %s, want %s", test.name, got, test.want)
+		}
+		if IsCyclic(test.v) != (test.want != "[]") {
+			t.Errorf("IsCyclic(%s) = %t", test.name, IsCyclic(test.v))
+		}
+	}
+}
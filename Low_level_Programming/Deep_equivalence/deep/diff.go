@@ -0,0 +1,238 @@
+package deep
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"unsafe"
+)
+
+/*
+	Diff walks two values like equal does, but instead of stopping at the first
+	difference it reports all of them, each with the path that leads to it:
+
+	.Actors[2]       element of a slice or array
+	["key"]          entry of a map
+	.Sequel->        value a pointer points to
+
+	Elements past the end of the shorter slice and keys found in only one map are
+	reported as added or removed. The seen map of equal is kept, so diffing cyclic
+	values terminates.
+*/
+
+// ChangeKind tells how a value differs
+type ChangeKind int
+
+// Kinds of change
+const (
+	Changed ChangeKind = iota
+	Added
+	Removed
+	TypeMismatch
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case Changed:
+		return "changed"
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	case TypeMismatch:
+		return "type mismatch"
+	}
+	return fmt.Sprintf("ChangeKind(%d)", int(k))
+}
+
+// Difference between x and y at Path. X is invalid for Added, Y for Removed.
+type Difference struct {
+	Path string
+	Kind ChangeKind
+	X, Y reflect.Value
+}
+
+func (d Difference) String() string {
+	switch d.Kind {
+	case Added:
+		return fmt.Sprintf("%s: added %s", d.path(), formatValue(d.Y))
+	case Removed:
+		return fmt.Sprintf("%s: removed %s", d.path(), formatValue(d.X))
+	case TypeMismatch:
+		return fmt.Sprintf("%s: type %s != %s", d.path(), typeOf(d.X), typeOf(d.Y))
+	}
+	return fmt.Sprintf("%s: %s != %s", d.path(), formatValue(d.X), formatValue(d.Y))
+}
+
+func (d Difference) path() string {
+	if d.Path == "" {
+		return "."
+	}
+	return d.Path
+}
+
+func formatValue(v reflect.Value) string {
+	if !v.IsValid() {
+		return "<none>"
+	}
+	return fmt.Sprintf("%#v", v)
+}
+
+func typeOf(v reflect.Value) string {
+	if !v.IsValid() {
+		return "nil"
+	}
+	return v.Type().String()
+}
+
+// Diff returns the differences between x and y, none if they are Equal
+func Diff(x, y interface{}) []Difference {
+	d := &differ{seen: make(map[comparison]bool)}
+	d.diff("", reflect.ValueOf(x), reflect.ValueOf(y))
+	return d.diffs
+}
+
+type differ struct {
+	seen  map[comparison]bool
+	diffs []Difference
+}
+
+func (d *differ) add(path string, kind ChangeKind, x, y reflect.Value) {
+	d.diffs = append(d.diffs, Difference{path, kind, x, y})
+}
+
+func (d *differ) diff(path string, x, y reflect.Value) {
+	if !x.IsValid() || !y.IsValid() {
+		if x.IsValid() != y.IsValid() {
+			d.add(path, TypeMismatch, x, y)
+		}
+		return
+	}
+	if x.Type() != y.Type() {
+		d.add(path, TypeMismatch, x, y)
+		return
+	}
+
+	if x.CanAddr() && y.CanAddr() {
+		xptr := unsafe.Pointer(x.UnsafeAddr())
+		yptr := unsafe.Pointer(y.UnsafeAddr())
+		if xptr == yptr {
+			return
+		}
+	}
+	if visit(d.seen, x, y) {
+		return
+	}
+
+	switch x.Kind() {
+	case reflect.Ptr:
+		if x.IsNil() != y.IsNil() {
+			d.add(path, Changed, x, y)
+			return
+		}
+		d.diff(path+"->", x.Elem(), y.Elem())
+	case reflect.Interface:
+		if x.IsNil() != y.IsNil() {
+			d.add(path, Changed, x, y)
+			return
+		}
+		d.diff(path, x.Elem(), y.Elem())
+	case reflect.Array, reflect.Slice:
+		n := x.Len()
+		if y.Len() < n {
+			n = y.Len()
+		}
+		for i := 0; i < n; i++ {
+			d.diff(fmt.Sprintf("%s[%d]", path, i), x.Index(i), y.Index(i))
+		}
+		for i := n; i < x.Len(); i++ {
+			d.add(fmt.Sprintf("%s[%d]", path, i), Removed, x.Index(i), reflect.Value{})
+		}
+		for i := n; i < y.Len(); i++ {
+			d.add(fmt.Sprintf("%s[%d]", path, i), Added, reflect.Value{}, y.Index(i))
+		}
+	case reflect.Struct:
+		for i, n := 0, x.NumField(); i < n; i++ {
+			d.diff(path+"."+x.Type().Field(i).Name, x.Field(i), y.Field(i))
+		}
+	case reflect.Map:
+		for _, k := range sortedKeys(x, y) {
+			kpath := fmt.Sprintf("%s[%#v]", path, k)
+			xv, yv := x.MapIndex(k), y.MapIndex(k)
+			switch {
+			case !yv.IsValid():
+				d.add(kpath, Removed, xv, yv)
+			case !xv.IsValid():
+				d.add(kpath, Added, xv, yv)
+			default:
+				d.diff(kpath, xv, yv)
+			}
+		}
+	default:
+		if !sameAtom(x, y) {
+			d.add(path, Changed, x, y)
+		}
+	}
+}
+
+// sortedKeys returns the keys of both maps, in the order of their printed form
+func sortedKeys(x, y reflect.Value) []reflect.Value {
+	var keys []reflect.Value
+	names := make(map[string]bool)
+	for _, m := range []reflect.Value{x, y} {
+		for _, k := range m.MapKeys() {
+			name := fmt.Sprintf("%#v", k)
+			if !names[name] {
+				names[name] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return fmt.Sprintf("%#v", keys[i]) < fmt.Sprintf("%#v", keys[j])
+	})
+	return keys
+}
+
+// sameAtom compares values that have no elements
+func sameAtom(x, y reflect.Value) bool {
+	switch x.Kind() {
+	case reflect.Bool:
+		return x.Bool() == y.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return x.Int() == y.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Uintptr:
+		return x.Uint() == y.Uint()
+	case reflect.Float32, reflect.Float64:
+		return math.Abs(x.Float()-y.Float()) < epsilon
+	case reflect.Complex64, reflect.Complex128:
+		return x.Complex() == y.Complex()
+	case reflect.String:
+		return x.String() == y.String()
+	case reflect.Chan, reflect.UnsafePointer, reflect.Func:
+		return x.Pointer() == y.Pointer()
+	}
+	return false
+}
+
+// Unified renders differences in the style of a unified diff
+func Unified(diffs []Difference) string {
+	var b strings.Builder
+	if len(diffs) == 0 {
+		return b.String()
+	}
+	b.WriteString("--- x\n+++ y\n")
+	for _, d := range diffs {
+		fmt.Fprintf(&b, "@@ %s @@ %s\n", d.path(), d.Kind)
+		if d.X.IsValid() || d.Kind == TypeMismatch {
+			fmt.Fprintf(&b, "-%s\n", formatValue(d.X))
+		}
+		if d.Y.IsValid() || d.Kind == TypeMismatch {
+			fmt.Fprintf(&b, "+%s\n", formatValue(d.Y))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,82 @@
+package deep
+
+import (
+	"strings"
+	"testing"
+)
+
+type film struct {
+	Title  string
+	Actors []string
+	Cast   map[string]string
+	Sequel *film
+	Extra  interface{}
+	rating uint8
+}
+
+func TestDiff(t *testing.T) {
+	x := film{
+		Title:  "Dr. Strangelove",
+		Actors: []string{"Sellers", "Scott", "Hayden"},
+		Cast:   map[string]string{"Kong": "Pickens", "Mandrake": "Sellers"},
+		Sequel: &film{Title: "II"},
+		Extra:  1,
+		rating: 5,
+	}
+	y := film{
+		Title:  "Dr. Strangelove",
+		Actors: []string{"Sellers", "Scott", "Wynn", "Jones"},
+		Cast:   map[string]string{"Kong": "Pickens", "Muffley": "Sellers"},
+		Sequel: &film{Title: "III"},
+		Extra:  "one",
+		rating: 4,
+	}
+	var got []string
+	for _, d := range Diff(x, y) {
+		got = append(got, d.String())
+	}
+	want := []string{
+		`.Actors[2]: "Hayden" != "Wynn"`,
+		`.Actors[3]: added "Jones"`,
+		`.Cast["Mandrake"]: removed "Sellers"`,
+		`.Cast["Muffley"]: added "Sellers"`,
+		`.Sequel->.Title: "II" != "III"`,
+		`.Extra: type int != string`,
+		`.rating: 0x5 != 0x4`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Diff =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if diffs := Diff(x, x); len(diffs) != 0 {
+		t.Errorf("Diff(x, x) = %v", diffs)
+	}
+	if u := Unified(Diff(x, y)); !strings.Contains(u, "@@ .Actors[2] @@ changed\n-\"Hayden\"\n+\"Wynn\"\n") {
+		t.Errorf("Unified =\n%s", u)
+	}
+}
+
+func TestDiffCycle(t *testing.T) {
+	type link struct {
+		Value int
+		Next  *link
+	}
+	a, b := &link{Value: 1}, &link{Value: 1}
+	a.Next, b.Next = a, &link{Value: 2, Next: b}
+	diffs := Diff(a, b)
+	if len(diffs) != 1 || diffs[0].Path != "->.Next->.Value" {
+		t.Errorf("Diff of cyclic lists = %v", diffs)
+	}
+}
+
+func TestDiffCyclicMaps(t *testing.T) {
+	m := map[string]interface{}{"n": 1}
+	n := map[string]interface{}{"n": 1}
+	m["self"], n["self"] = m, n
+	if diffs := Diff(m, n); len(diffs) != 0 {
+		t.Errorf("Diff of equal cyclic maps = %v", diffs)
+	}
+	n["n"] = 2
+	if diffs := Diff(m, n); len(diffs) != 1 || diffs[0].Path != `["n"]` {
+		t.Errorf("Diff of cyclic maps = %v", diffs)
+	}
+}
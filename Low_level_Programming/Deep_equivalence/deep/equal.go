@@ -0,0 +1,144 @@
+// Package deep compares Go values deeply: Equal and EqualWith, Diff and
+// FindCycles.
+package deep
+
+import (
+	"fmt"
+	"reflect"
+	"unsafe"
+)
+
+// equal
+
+const epsilon = 0.0000001
+
+func (o *options) equal(path string, x, y reflect.Value, seen map[comparison]bool) bool {
+	if !x.IsValid() || !y.IsValid() {
+		return x.IsValid() == y.IsValid()
+	}
+	if x.Type() != y.Type() {
+		return false
+	}
+	if fn, ok := o.comparers[x.Type()]; ok {
+		if x, y := exported(x), exported(y); x.CanInterface() && y.CanInterface() {
+			return fn.Call([]reflect.Value{x, y})[0].Bool()
+		}
+	}
+
+	if x.CanAddr() && y.CanAddr() {
+		xptr := unsafe.Pointer(x.UnsafeAddr())
+		yptr := unsafe.Pointer(y.UnsafeAddr())
+		if xptr == yptr {
+			return true
+		}
+	}
+	if visit(seen, x, y) {
+		return true
+	}
+
+	switch x.Kind() {
+	case reflect.Bool:
+		return x.Bool() == y.Bool()
+	case reflect.Chan, reflect.UnsafePointer, reflect.Func:
+		return x.Pointer() == y.Pointer()
+	case reflect.Ptr:
+		return o.equal(path+"->", x.Elem(), y.Elem(), seen)
+	case reflect.Interface:
+		return o.equal(path, x.Elem(), y.Elem(), seen)
+	case reflect.Array, reflect.Slice:
+		if x.Len() != y.Len() {
+			return false
+		}
+		if x.Kind() == reflect.Slice {
+			if x.IsNil() != y.IsNil() && !o.equateEmpty {
+				return false
+			}
+			if less, ok := o.sorters[x.Type().Elem()]; ok {
+				x, y = sorted(x, less), sorted(y, less)
+			}
+		}
+		for i := 0; i < x.Len(); i++ {
+			if !o.equal(fmt.Sprintf("%s[%d]", path, i), x.Index(i), y.Index(i), seen) {
+				return false
+			}
+		}
+		return true
+	case reflect.Struct:
+		for i, n := 0, x.NumField(); i < n; i++ {
+			fpath := path + "." + x.Type().Field(i).Name
+			if o.ignored(fpath, x.Type().Field(i)) {
+				continue
+			}
+			if !o.equal(fpath, x.Field(i), y.Field(i), seen) {
+				return false
+			}
+		}
+		return true
+	case reflect.Map:
+		if x.Len() != y.Len() {
+			return false
+		}
+		if x.IsNil() != y.IsNil() && !o.equateEmpty {
+			return false
+		}
+		for _, k := range x.MapKeys() {
+			yv := y.MapIndex(k)
+			if !yv.IsValid() {
+				return false
+			}
+			if !o.equal(fmt.Sprintf("%s[%#v]", path, k), x.MapIndex(k), yv, seen) {
+				return false
+			}
+		}
+		return true
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64: // Ejercicio 13.1
+		return x.Int() == y.Int()
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Uintptr:
+		return x.Uint() == y.Uint()
+
+	case reflect.Float32, reflect.Float64: // Ejercicio 13.1
+		return o.near(x.Float(), y.Float())
+
+	case reflect.Complex64, reflect.Complex128:
+		cx, cy := x.Complex(), y.Complex()
+		return o.near(real(cx), real(cy)) && o.near(imag(cx), imag(cy))
+
+	case reflect.String:
+		return x.String() == y.String()
+
+	}
+	return false
+}
+
+type comparison struct {
+	x, y unsafe.Pointer
+	t    reflect.Type
+}
+
+// visit records that x and y are being compared, and reports whether they were
+// already. Maps, slices and pointers are known by what they point to, since those
+// reached through a map are not addressable; other values by their address.
+func visit(seen map[comparison]bool, x, y reflect.Value) bool {
+	var c comparison
+	switch k := x.Kind(); {
+	case (k == reflect.Map || k == reflect.Slice || k == reflect.Ptr) && !x.IsNil() && !y.IsNil():
+		c = comparison{unsafe.Pointer(x.Pointer()), unsafe.Pointer(y.Pointer()), x.Type()}
+	case x.CanAddr() && y.CanAddr():
+		c = comparison{unsafe.Pointer(x.UnsafeAddr()), unsafe.Pointer(y.UnsafeAddr()), x.Type()}
+	default:
+		return false
+	}
+	if seen[c] {
+		return true
+	}
+	seen[c] = true
+	return false
+}
+
+// Equal function
+func Equal(x, y interface{}) bool {
+	return EqualWith(x, y, AbsTolerance(epsilon), EquateEmpty())
+}
@@ -0,0 +1,178 @@
+package deep
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unsafe"
+)
+
+/*
+	EqualWith is Equal with options. Without any, floats must be exactly equal and a
+	nil slice or map differs from an empty one; Equal is EqualWith(x, y,
+	AbsTolerance(epsilon), EquateEmpty()).
+
+	IgnorePath takes paths written as in Diff, with [*] standing for any index or key:
+
+	EqualWith(x, y, IgnorePath(".Sequel->.Title", ".Actors[*].Name"))
+
+	IgnoreTag skips the fields whose tag has an option, as `json:"-"` for
+	IgnoreTag("json", "-"). Comparer and SortSlices take functions on the element
+	type, func(a, b T) bool, and apply wherever a T is found.
+*/
+
+// Option of EqualWith
+type Option func(*options)
+
+type options struct {
+	abs, rel    float64
+	equateEmpty bool
+	paths       []*regexp.Regexp
+	tags        [][2]string
+	comparers   map[reflect.Type]reflect.Value
+	sorters     map[reflect.Type]reflect.Value
+}
+
+// EqualWith function
+func EqualWith(x, y interface{}, opts ...Option) bool {
+	o := &options{
+		comparers: make(map[reflect.Type]reflect.Value),
+		sorters:   make(map[reflect.Type]reflect.Value),
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	seen := make(map[comparison]bool)
+	return o.equal("", addressable(reflect.ValueOf(x)), addressable(reflect.ValueOf(y)), seen)
+}
+
+// AbsTolerance makes floats equal when they differ by less than eps
+func AbsTolerance(eps float64) Option {
+	return func(o *options) { o.abs = eps }
+}
+
+// RelTolerance makes floats equal when they differ by at most frac of the larger
+// magnitude
+func RelTolerance(frac float64) Option {
+	return func(o *options) { o.rel = frac }
+}
+
+// EquateEmpty makes nil and empty slices or maps equal
+func EquateEmpty() Option {
+	return func(o *options) { o.equateEmpty = true }
+}
+
+// IgnorePath skips the struct fields at the given paths
+func IgnorePath(paths ...string) Option {
+	return func(o *options) {
+		for _, p := range paths {
+			expr := strings.Replace(regexp.QuoteMeta(p), `\[\*\]`, `\[[^\]]*\]`, -1)
+			o.paths = append(o.paths, regexp.MustCompile("^"+expr+"$"))
+		}
+	}
+}
+
+// IgnoreTag skips the struct fields whose tag key lists value
+func IgnoreTag(key, value string) Option {
+	return func(o *options) { o.tags = append(o.tags, [2]string{key, value}) }
+}
+
+// Comparer compares values of type T with fn, a func(x, y T) bool
+func Comparer(fn interface{}) Option {
+	v := funcOfPair(fn, "Comparer")
+	return func(o *options) { o.comparers[v.Type().In(0)] = v }
+}
+
+// SortSlices sorts slices of T with less, a func(a, b T) bool, before comparing
+// them, so the order of their elements does not matter
+func SortSlices(less interface{}) Option {
+	v := funcOfPair(less, "SortSlices")
+	return func(o *options) { o.sorters[v.Type().In(0)] = v }
+}
+
+// funcOfPair checks that fn is a func(T, T) bool
+func funcOfPair(fn interface{}, name string) reflect.Value {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func || v.Type().NumIn() != 2 || v.Type().NumOut() != 1 ||
+		v.Type().In(0) != v.Type().In(1) || v.Type().Out(0).Kind() != reflect.Bool {
+		panic(fmt.Sprintf("%s: want func(T, T) bool, got %T", name, fn))
+	}
+	return v
+}
+
+// ignored reports whether the struct field at path is skipped
+func (o *options) ignored(path string, f reflect.StructField) bool {
+	for _, re := range o.paths {
+		if re.MatchString(path) {
+			return true
+		}
+	}
+	for _, tag := range o.tags {
+		for _, opt := range strings.Split(f.Tag.Get(tag[0]), ",") {
+			if opt == tag[1] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// near compares floats with the tolerances of o
+func (o *options) near(x, y float64) bool {
+	if x == y {
+		return true
+	}
+	d := math.Abs(x - y)
+	if d < o.abs {
+		return true
+	}
+	return d <= o.rel*math.Max(math.Abs(x), math.Abs(y))
+}
+
+// addressable returns a copy of v that can be addressed, so unexported fields
+// below it can be handed to comparers and their pointers remembered
+func addressable(v reflect.Value) reflect.Value {
+	if !v.IsValid() || v.CanAddr() {
+		return v
+	}
+	c := reflect.New(v.Type()).Elem()
+	c.Set(v)
+	return c
+}
+
+// exported returns v without the read-only flag of unexported fields, when v can
+// be addressed
+func exported(v reflect.Value) reflect.Value {
+	if v.CanInterface() || !v.CanAddr() {
+		return v
+	}
+	return reflect.NewAt(v.Type(), unsafe.Pointer(v.UnsafeAddr())).Elem()
+}
+
+// sorted returns a sorted copy of the slice s
+func sorted(s, less reflect.Value) reflect.Value {
+	if s.IsNil() {
+		return s
+	}
+	c := reflect.MakeSlice(s.Type(), s.Len(), s.Len())
+	for i := 0; i < s.Len(); i++ {
+		c.Index(i).Set(exported(s.Index(i)))
+	}
+	swap := reflect.Swapper(c.Interface())
+	sort.Sort(&byLess{c, less, swap})
+	return c
+}
+
+type byLess struct {
+	s, less reflect.Value
+	swap    func(i, j int)
+}
+
+func (b *byLess) Len() int      { return b.s.Len() }
+func (b *byLess) Swap(i, j int) { b.swap(i, j) }
+func (b *byLess) Less(i, j int) bool {
+	return b.less.Call([]reflect.Value{b.s.Index(i), b.s.Index(j)})[0].Bool()
+}
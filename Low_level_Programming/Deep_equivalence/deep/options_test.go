@@ -0,0 +1,128 @@
+package deep
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+type record struct {
+	ID      int `json:"-"`
+	Name    string
+	Tags    []string
+	Scores  map[string]float64
+	Next    *record
+	updated int64
+}
+
+func TestEqualWith(t *testing.T) {
+	one := 1
+	fold := Comparer(func(a, b string) bool { return strings.EqualFold(a, b) })
+	byText := SortSlices(func(a, b string) bool { return a < b })
+	tests := []struct {
+		x, y interface{}
+		opts []Option
+		want bool
+	}{
+		{1.0, 1.0000001, nil, false},
+		{1.0, 1.0000001, []Option{AbsTolerance(1e-6)}, true},
+		{1000.0, 1001.0, []Option{RelTolerance(0.01)}, true},
+		{1000.0, 1011.0, []Option{RelTolerance(0.01)}, false},
+		{math.NaN(), math.NaN(), nil, false},
+		{complex(1, 2), complex(1, 2.0000001), []Option{AbsTolerance(1e-6)}, true},
+		{uint8(1), uint8(2), nil, false},
+		{uintptr(3), uintptr(3), nil, true},
+		{int16(-4), int16(-4), nil, true},
+		{[]int(nil), []int{}, nil, false},
+		{[]int(nil), []int{}, []Option{EquateEmpty()}, true},
+		{map[string]int(nil), map[string]int{}, []Option{EquateEmpty()}, true},
+		{map[string]int{"a": 0}, map[string]int{"b": 0}, nil, false},
+		{[]string{"b", "a"}, []string{"a", "b"}, nil, false},
+		{[]string{"b", "a"}, []string{"a", "b"}, []Option{byText}, true},
+		{"Go", "GO", []Option{fold}, true},
+		{[]string{"Go"}, []string{"go"}, []Option{fold}, true},
+		{unsafe.Pointer(&one), unsafe.Pointer(&one), nil, true},
+		{make(chan int), make(chan int), nil, false},
+		{[2]bool{true}, [2]bool{true}, nil, true},
+		{interface{}(nil), 0, nil, false},
+	}
+	for _, test := range tests {
+		if got := EqualWith(test.x, test.y, test.opts...); got != test.want {
+			t.Errorf("EqualWith(%#v, %#v, %d options) = %t", test.x, test.y, len(test.opts), got)
+		}
+	}
+}
+
+func TestEqualWithFields(t *testing.T) {
+	x := record{ID: 1, Name: "a", Tags: []string{"x"}, Next: &record{Name: "b"}, updated: 10}
+	y := record{ID: 2, Name: "a", Tags: []string{"y"}, Next: &record{Name: "c"}, updated: 20}
+	if EqualWith(x, y) {
+		t.Fatal("EqualWith(x, y) = true")
+	}
+	ignore := []Option{
+		IgnoreTag("json", "-"),
+		IgnorePath(".Tags", ".Next->.Name", ".updated"),
+	}
+	if !EqualWith(x, y, ignore...) {
+		t.Errorf("EqualWith(x, y) ignoring the differences = false")
+	}
+	if EqualWith(x, y, ignore[1:]...) {
+		t.Errorf("EqualWith(x, y) without IgnoreTag = true")
+	}
+
+	list := []record{{ID: 1, Name: "a"}, {ID: 2, Name: "b"}}
+	other := []record{{ID: 3, Name: "a"}, {ID: 4, Name: "b"}}
+	if !EqualWith(list, other, IgnorePath("[*].ID")) {
+		t.Errorf("EqualWith ignoring [*].ID = false")
+	}
+
+	// comparers see unexported fields too
+	stamps := Comparer(func(a, b int64) bool { return a/100 == b/100 })
+	x.ID, x.Tags, x.Next = y.ID, y.Tags, y.Next
+	if !EqualWith(x, y, stamps) {
+		t.Errorf("EqualWith(x, y) with a comparer of int64 = false")
+	}
+}
+
+func TestEqualWithCycle(t *testing.T) {
+	a, b := &record{Name: "a"}, &record{Name: "a"}
+	a.Next, b.Next = a, b
+	if !EqualWith(a, b) {
+		t.Errorf("EqualWith of equal cycles = false")
+	}
+}
+
+func TestEqualWithCyclicMaps(t *testing.T) {
+	m := map[string]interface{}{"n": 1}
+	n := map[string]interface{}{"n": 1}
+	m["self"], n["self"] = m, n
+	if !EqualWith(m, n) {
+		t.Errorf("EqualWith of equal cyclic maps = false")
+	}
+	n["n"] = 2
+	if EqualWith(m, n) {
+		t.Errorf("EqualWith of different cyclic maps = true")
+	}
+}
+
+func TestEqualLegacy(t *testing.T) {
+	if !Equal(1.0, 1.00000001) || Equal(1.0, 1.001) {
+		t.Errorf("Equal does not use epsilon")
+	}
+	if !Equal(map[string]int(nil), map[string]int{}) {
+		t.Errorf("Equal(nil map, empty map) = false")
+	}
+	if Equal(uint(1), uint(2)) || !Equal(complex64(1i), complex64(1i)) {
+		t.Errorf("Equal of uint or complex is wrong")
+	}
+}
+
+func TestOptionSignature(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Comparer(func(int) bool) did not panic")
+		}
+	}()
+	Comparer(func(int) bool { return true })
+}
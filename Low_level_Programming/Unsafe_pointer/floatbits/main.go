@@ -0,0 +1,101 @@
+// Floatbits prints the IEEE 754 fields of floats, their neighbours, and the ULP
+// distance between two of them.
+//
+//	floatbits [-32] 0.1 -0 NaN 0x7ff4000000000000
+//	floatbits [-32] -ulp 0.3 0.30000000000000004
+//
+// A value is a decimal or hexadecimal float literal, Inf or NaN, or a raw bit
+// pattern written in hex, 0x followed by the digits and no p exponent.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"unsafe_packages/ieee754"
+)
+
+var (
+	single = flag.Bool("32", false, "float32 instead of float64")
+	ulp    = flag.Bool("ulp", false, "print the ULP distance between two values")
+)
+
+func main() {
+	flag.Parse()
+	width := 64
+	if *single {
+		width = 32
+	}
+	var values []ieee754.Bits
+	for _, arg := range flag.Args() {
+		b, err := parse(arg, width)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "floatbits: %v\n", err)
+			os.Exit(2)
+		}
+		values = append(values, b)
+	}
+
+	if *ulp {
+		if len(values) != 2 {
+			fmt.Fprintf(os.Stderr, "floatbits: -ulp takes two values\n")
+			os.Exit(2)
+		}
+		var n uint64
+		var err error
+		if width == 32 {
+			n, err = ieee754.ULP32(values[0].Float32(), values[1].Float32())
+		} else {
+			n, err = ieee754.ULP64(values[0].Float64(), values[1].Float64())
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "floatbits: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("%v and %v are %d ULP apart\n", show(values[0]), show(values[1]), n)
+		return
+	}
+
+	for _, b := range values {
+		fmt.Println(show(b))
+		fmt.Printf("  bits      0x%0*x\n", width/4, b.Raw)
+		fmt.Printf("  sign      %d\n", b.Sign)
+		fmt.Printf("  exponent  %#x biased, %d unbiased\n", b.Exponent, b.Unbiased())
+		fmt.Printf("  mantissa  %#x\n", b.Mantissa)
+		fmt.Printf("  class     %s\n", b.Class())
+		if width == 32 {
+			below, above := ieee754.Next32(b.Float32())
+			fmt.Printf("  below     %v\n  above     %v\n", below, above)
+		} else {
+			below, above := ieee754.Next64(b.Float64())
+			fmt.Printf("  below     %v\n  above     %v\n", below, above)
+		}
+	}
+}
+
+// parse reads a raw bit pattern or a float literal
+func parse(s string, width int) (ieee754.Bits, error) {
+	lower := strings.ToLower(s)
+	if strings.HasPrefix(lower, "0x") && !strings.Contains(lower, "p") {
+		return ieee754.ParseHex(s, width)
+	}
+	f, err := strconv.ParseFloat(s, width)
+	if err != nil {
+		return ieee754.Bits{}, err
+	}
+	if width == 32 {
+		return ieee754.Decode32(float32(f)), nil
+	}
+	return ieee754.Decode64(f), nil
+}
+
+// show prints the value of b with all the digits needed to read it back
+func show(b ieee754.Bits) string {
+	if b.Width == 32 {
+		return strconv.FormatFloat(float64(b.Float32()), 'g', -1, 32)
+	}
+	return strconv.FormatFloat(b.Float64(), 'g', -1, 64)
+}
@@ -0,0 +1,205 @@
+// Package ieee754 takes float32 and float64 values apart into the fields of their
+// IEEE 754 representation, with the pointer conversion of Float64bit:
+//
+//	float32  sign 1  exponent 8   mantissa 23 bits
+//	float64  sign 1  exponent 11  mantissa 52 bits
+//
+// The exponent is stored with a bias of 127 or 1023. All ones is infinity when the
+// mantissa is zero and NaN otherwise, quiet if the top bit of the mantissa is set
+// and signalling if not. All zeros is zero or a subnormal number, whose exponent is
+// that of the smallest normal one.
+package ieee754
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unsafe"
+)
+
+// Float64bit returns the bits of f
+func Float64bit(f float64) uint64 { return *(*uint64)(unsafe.Pointer(&f)) }
+
+// Float32bit returns the bits of f
+func Float32bit(f float32) uint32 { return *(*uint32)(unsafe.Pointer(&f)) }
+
+// Class of a float
+type Class int
+
+// Classes
+const (
+	Normal Class = iota
+	Subnormal
+	Zero
+	Inf
+	QuietNaN
+	SignallingNaN
+)
+
+func (c Class) String() string {
+	switch c {
+	case Normal:
+		return "normal"
+	case Subnormal:
+		return "subnormal"
+	case Zero:
+		return "zero"
+	case Inf:
+		return "infinity"
+	case QuietNaN:
+		return "quiet NaN"
+	case SignallingNaN:
+		return "signalling NaN"
+	}
+	return "Class(" + strconv.Itoa(int(c)) + ")"
+}
+
+// format of a width
+type format struct {
+	expBits, mantBits uint
+	bias              int
+}
+
+var formats = map[int]format{
+	32: {8, 23, 127},
+	64: {11, 52, 1023},
+}
+
+// Bits is a float taken apart
+type Bits struct {
+	Width    int // 32 or 64
+	Raw      uint64
+	Sign     uint64 // 1 for negative
+	Exponent uint64 // biased
+	Mantissa uint64 // without the implicit leading 1
+}
+
+// Decode64 takes f apart
+func Decode64(f float64) Bits { return fromRaw(64, Float64bit(f)) }
+
+// Decode32 takes f apart
+func Decode32(f float32) Bits { return fromRaw(32, uint64(Float32bit(f))) }
+
+func fromRaw(width int, raw uint64) Bits {
+	fm := formats[width]
+	return Bits{
+		Width:    width,
+		Raw:      raw,
+		Sign:     raw >> (fm.expBits + fm.mantBits) & 1,
+		Exponent: raw >> fm.mantBits & (1<<fm.expBits - 1),
+		Mantissa: raw & (1<<fm.mantBits - 1),
+	}
+}
+
+// ParseHex reads a raw bit pattern of width 32 or 64, as 0x3ff0000000000000
+func ParseHex(s string, width int) (Bits, error) {
+	if _, ok := formats[width]; !ok {
+		return Bits{}, fmt.Errorf("ieee754: width %d is not 32 or 64", width)
+	}
+	digits := strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X")
+	raw, err := strconv.ParseUint(strings.Replace(digits, "_", "", -1), 16, width)
+	if err != nil {
+		return Bits{}, fmt.Errorf("ieee754: bad %d-bit pattern %q", width, s)
+	}
+	return fromRaw(width, raw), nil
+}
+
+// Float64 returns the value of b, widened if b is a float32
+func (b Bits) Float64() float64 {
+	if b.Width == 32 {
+		return float64(b.Float32())
+	}
+	return math.Float64frombits(b.Raw)
+}
+
+// Float32 returns the value of b, rounded if b is a float64
+func (b Bits) Float32() float32 {
+	if b.Width == 32 {
+		return math.Float32frombits(uint32(b.Raw))
+	}
+	return float32(math.Float64frombits(b.Raw))
+}
+
+// Unbiased returns the exponent of b as a power of two
+func (b Bits) Unbiased() int {
+	fm := formats[b.Width]
+	if b.Exponent == 0 {
+		return 1 - fm.bias
+	}
+	return int(b.Exponent) - fm.bias
+}
+
+// Class of b
+func (b Bits) Class() Class {
+	fm := formats[b.Width]
+	switch b.Exponent {
+	case 0:
+		if b.Mantissa == 0 {
+			return Zero
+		}
+		return Subnormal
+	case 1<<fm.expBits - 1:
+		if b.Mantissa == 0 {
+			return Inf
+		}
+		if b.Mantissa>>(fm.mantBits-1) == 1 {
+			return QuietNaN
+		}
+		return SignallingNaN
+	}
+	return Normal
+}
+
+func (b Bits) String() string {
+	sign := "+"
+	if b.Sign == 1 {
+		sign = "-"
+	}
+	return fmt.Sprintf("0x%0*x sign %s exponent %#x (2^%d) mantissa %#x %s",
+		b.Width/4, b.Raw, sign, b.Exponent, b.Unbiased(), b.Mantissa, b.Class())
+}
+
+// Next64 returns the representable values on either side of f
+func Next64(f float64) (below, above float64) {
+	return math.Nextafter(f, math.Inf(-1)), math.Nextafter(f, math.Inf(1))
+}
+
+// Next32 returns the representable values on either side of f
+func Next32(f float32) (below, above float32) {
+	return math.Nextafter32(f, float32(math.Inf(-1))), math.Nextafter32(f, float32(math.Inf(1)))
+}
+
+// ULP64 returns how many representable values apart x and y are, 0 for equal
+// values and for +0 and -0
+func ULP64(x, y float64) (uint64, error) {
+	if math.IsNaN(x) || math.IsNaN(y) {
+		return 0, fmt.Errorf("ieee754: no distance to NaN")
+	}
+	return distance(ordered(Float64bit(x), 63), ordered(Float64bit(y), 63)), nil
+}
+
+// ULP32 is ULP64 for float32 values
+func ULP32(x, y float32) (uint64, error) {
+	if x != x || y != y {
+		return 0, fmt.Errorf("ieee754: no distance to NaN")
+	}
+	return distance(ordered(uint64(Float32bit(x)), 31), ordered(uint64(Float32bit(y)), 31)), nil
+}
+
+// ordered maps the bits of a float to an integer that grows with the float, so
+// neighbours differ by one
+func ordered(raw uint64, signBit uint) int64 {
+	magnitude := int64(raw &^ (1 << signBit))
+	if raw>>signBit&1 == 1 {
+		return -magnitude
+	}
+	return magnitude
+}
+
+func distance(a, b int64) uint64 {
+	if a > b {
+		return uint64(a) - uint64(b)
+	}
+	return uint64(b) - uint64(a)
+}
@@ -0,0 +1,101 @@
+package ieee754
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDecode64(t *testing.T) {
+	tests := []struct {
+		f        float64
+		sign     uint64
+		exp      uint64
+		unbiased int
+		mant     uint64
+		class    Class
+	}{
+		{1, 0, 0x3ff, 0, 0, Normal},
+		{-2.5, 1, 0x400, 1, 0x4000000000000, Normal},
+		{0, 0, 0, -1022, 0, Zero},
+		{math.Copysign(0, -1), 1, 0, -1022, 0, Zero},
+		{5e-324, 0, 0, -1022, 1, Subnormal},
+		{math.Inf(-1), 1, 0x7ff, 1024, 0, Inf},
+		{math.NaN(), 0, 0x7ff, 1024, 0x8000000000001, QuietNaN},
+		{math.Float64frombits(0x7ff4000000000000), 0, 0x7ff, 1024, 0x4000000000000, SignallingNaN},
+	}
+	for _, test := range tests {
+		b := Decode64(test.f)
+		if b.Sign != test.sign || b.Exponent != test.exp || b.Unbiased() != test.unbiased ||
+			b.Mantissa != test.mant || b.Class() != test.class {
+			t.Errorf("Decode64(%v) = %v", test.f, b)
+		}
+		if got := b.Float64(); Float64bit(got) != Float64bit(test.f) {
+			t.Errorf("Decode64(%v).Float64() = %v", test.f, got)
+		}
+	}
+}
+
+func TestDecode32(t *testing.T) {
+	b := Decode32(-0.15625)
+	if b.Raw != 0xbe200000 || b.Sign != 1 || b.Exponent != 124 || b.Unbiased() != -3 ||
+		b.Mantissa != 0x200000 || b.Class() != Normal {
+		t.Errorf("Decode32(-0.15625) = %v", b)
+	}
+	if c := Decode32(math.Float32frombits(1)).Class(); c != Subnormal {
+		t.Errorf("smallest float32 is %s", c)
+	}
+	if c := Decode32(math.Float32frombits(0x7fa00000)).Class(); c != SignallingNaN {
+		t.Errorf("0x7fa00000 is %s", c)
+	}
+}
+
+func TestParseHex(t *testing.T) {
+	b, err := ParseHex("0x3ff0_0000_0000_0000", 64)
+	if err != nil || b.Float64() != 1 {
+		t.Errorf("ParseHex = %v, %v", b, err)
+	}
+	b, err = ParseHex("3F800000", 32)
+	if err != nil || b.Float32() != 1 {
+		t.Errorf("ParseHex(32 bits) = %v, %v", b, err)
+	}
+	for _, bad := range []string{"0x1ffffffff", "0xg"} {
+		if _, err := ParseHex(bad, 32); err == nil {
+			t.Errorf("ParseHex(%q, 32) did not fail", bad)
+		}
+	}
+	if _, err := ParseHex("0", 16); err == nil {
+		t.Errorf("ParseHex of width 16 did not fail")
+	}
+	if s := Decode64(1).String(); s != "0x3ff0000000000000 sign + exponent 0x3ff (2^0) mantissa 0x0 normal" {
+		t.Errorf("String() = %s", s)
+	}
+}
+
+func TestULP(t *testing.T) {
+	tests := []struct {
+		x, y float64
+		want uint64
+	}{
+		{0.3, 0.30000000000000004, 1},
+		{1, 1, 0},
+		{0, math.Copysign(0, -1), 0},
+		{-5e-324, 5e-324, 2},
+		{math.MaxFloat64, math.Inf(1), 1},
+		{1, math.Nextafter(1, 2), 1},
+	}
+	for _, test := range tests {
+		if got, err := ULP64(test.x, test.y); err != nil || got != test.want {
+			t.Errorf("ULP64(%v, %v) = %d, %v, want %d", test.x, test.y, got, err, test.want)
+		}
+	}
+	if _, err := ULP64(math.NaN(), 1); err == nil {
+		t.Errorf("ULP64(NaN, 1) did not fail")
+	}
+	below, above := Next32(1)
+	if n, _ := ULP32(below, above); n != 2 {
+		t.Errorf("ULP32 across 1 = %d", n)
+	}
+	if below, above := Next64(0); below != -5e-324 || above != 5e-324 {
+		t.Errorf("Next64(0) = %v, %v", below, above)
+	}
+}
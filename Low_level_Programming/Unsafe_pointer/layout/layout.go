@@ -0,0 +1,144 @@
+// Package layout reports how the fields of struct types are placed in memory: the
+// offsets, sizes and alignments given by unsafe.Offsetof, Sizeof and Alignof, the
+// padding the compiler inserts between fields, and the field order that wastes the
+// least of it.
+package layout
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// Field of a struct
+type Field struct {
+	Name    string
+	Type    string
+	Offset  int64
+	Size    int64
+	Align   int64
+	Padding int64 // bytes after the field, before the next one or the end
+}
+
+// Struct is the layout of a struct type
+type Struct struct {
+	Name   string
+	Pos    string // file:line of the declaration, when loaded from source
+	Size   int64
+	Align  int64
+	Fields []Field
+}
+
+// Of returns the layout of the struct type t, or of the struct t points to
+func Of(t reflect.Type) (*Struct, error) {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("layout: %s is not a struct", t)
+	}
+	s := &Struct{Name: t.String(), Size: int64(t.Size()), Align: int64(t.Align())}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		s.Fields = append(s.Fields, Field{
+			Name:   f.Name,
+			Type:   f.Type.String(),
+			Offset: int64(f.Offset),
+			Size:   int64(f.Type.Size()),
+			Align:  int64(f.Type.Align()),
+		})
+	}
+	s.pad()
+	return s, nil
+}
+
+// pad computes the padding after each field from the offsets and the size
+func (s *Struct) pad() {
+	for i := range s.Fields {
+		f := &s.Fields[i]
+		end := s.Size
+		if i+1 < len(s.Fields) {
+			end = s.Fields[i+1].Offset
+		}
+		f.Padding = end - f.Offset - f.Size
+	}
+}
+
+// Wasted returns the bytes of padding of s
+func (s *Struct) Wasted() int64 {
+	var n int64
+	for _, f := range s.Fields {
+		n += f.Padding
+	}
+	return n
+}
+
+// Optimal returns s with its fields in the order that minimizes padding: zero-sized
+// fields first, so none is left at the end where it would take a byte, then by
+// decreasing alignment and size. Since the size of every Go type is a multiple of
+// its alignment, only the padding at the end remains.
+func (s *Struct) Optimal() *Struct {
+	o := &Struct{Name: s.Name, Pos: s.Pos, Align: s.Align}
+	o.Fields = append([]Field(nil), s.Fields...)
+	sort.SliceStable(o.Fields, func(i, j int) bool {
+		a, b := o.Fields[i], o.Fields[j]
+		if (a.Size == 0) != (b.Size == 0) {
+			return a.Size == 0
+		}
+		if a.Align != b.Align {
+			return a.Align > b.Align
+		}
+		return a.Size > b.Size
+	})
+	var off int64
+	for i := range o.Fields {
+		f := &o.Fields[i]
+		off = roundUp(off, f.Align)
+		f.Offset = off
+		off += f.Size
+	}
+	o.Size = roundUp(off, o.Align)
+	o.pad()
+	return o
+}
+
+func roundUp(n, align int64) int64 {
+	if align <= 1 {
+		return n
+	}
+	return (n + align - 1) / align * align
+}
+
+// Order returns the names of the fields of s
+func (s *Struct) Order() []string {
+	names := make([]string, len(s.Fields))
+	for i, f := range s.Fields {
+		names[i] = f.Name
+	}
+	return names
+}
+
+// Write prints s as a table, followed by the optimal order when it is smaller
+func (s *Struct) Write(w io.Writer) error {
+	if s.Pos != "" {
+		fmt.Fprintf(w, "%s:\n", s.Pos)
+	}
+	fmt.Fprintf(w, "%s  size %d  align %d  wasted %d\n", s.Name, s.Size, s.Align, s.Wasted())
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "offset\tsize\talign\tpadding\t  field\n")
+	for _, f := range s.Fields {
+		fmt.Fprintf(tw, "%d\t%d\t%d\t%d\t  %s %s\n", f.Offset, f.Size, f.Align, f.Padding, f.Name, f.Type)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if o := s.Optimal(); o.Size < s.Size {
+		_, err := fmt.Fprintf(w, "reordered as %s: size %d, saves %d bytes\n",
+			strings.Join(o.Order(), ", "), o.Size, s.Size-o.Size)
+		return err
+	}
+	return nil
+}
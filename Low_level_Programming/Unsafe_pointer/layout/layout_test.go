@@ -0,0 +1,139 @@
+package layout
+
+import (
+	"go/types"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+type padded struct {
+	a bool
+	b int64
+	c bool
+	d int32
+	e struct{}
+}
+
+func TestOf(t *testing.T) {
+	var x padded
+	s, err := Of(reflect.TypeOf(&x))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Size != int64(unsafe.Sizeof(x)) || s.Align != int64(unsafe.Alignof(x)) {
+		t.Errorf("size %d align %d, want %d %d", s.Size, s.Align, unsafe.Sizeof(x), unsafe.Alignof(x))
+	}
+	offsets := []uintptr{unsafe.Offsetof(x.a), unsafe.Offsetof(x.b), unsafe.Offsetof(x.c),
+		unsafe.Offsetof(x.d), unsafe.Offsetof(x.e)}
+	var used int64
+	for i, f := range s.Fields {
+		if f.Offset != int64(offsets[i]) {
+			t.Errorf("%s at %d, want %d", f.Name, f.Offset, offsets[i])
+		}
+		used += f.Size
+	}
+	if s.Wasted() != s.Size-used {
+		t.Errorf("Wasted() = %d, want %d", s.Wasted(), s.Size-used)
+	}
+
+	o := s.Optimal()
+	if got := strings.Join(o.Order(), " "); got != "e b d a c" {
+		t.Errorf("optimal order %s", got)
+	}
+	if o.Size != 16 || o.Wasted() != 2 {
+		t.Errorf("optimal size %d wasted %d, want 16 and 2", o.Size, o.Wasted())
+	}
+
+	if _, err := Of(reflect.TypeOf(1)); err == nil {
+		t.Errorf("Of(int) did not fail")
+	}
+}
+
+func TestTrailingZeroSize(t *testing.T) {
+	type tail struct {
+		n int32
+		z [0]int64
+	}
+	s, _ := Of(reflect.TypeOf(tail{}))
+	o := s.Optimal()
+	if s.Size != int64(unsafe.Sizeof(tail{})) || o.Size != 8 || o.Order()[0] != "z" {
+		t.Errorf("size %d, optimal %v of size %d", s.Size, o.Order(), o.Size)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	src := `package hot
+
+import "time"
+
+type Event struct {
+	Seen    bool
+	At      time.Time
+	Retries uint8
+	ID      int64
+	Tags    []string
+}
+
+type Count int
+`
+	if err := os.WriteFile(filepath.Join(dir, "hot.go"), []byte(src), 0666); err != nil {
+		t.Fatal(err)
+	}
+	structs, err := LoadTree(dir, types.SizesFor("gc", "amd64"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(structs) != 1 {
+		t.Fatalf("loaded %d structs, want 1", len(structs))
+	}
+	s := structs[0]
+	if s.Name != "hot.Event" || !strings.HasSuffix(s.Pos, "hot.go:5:6") {
+		t.Errorf("loaded %s at %s", s.Name, s.Pos)
+	}
+	if s.Fields[1].Type != "time.Time" || s.Fields[1].Offset != 8 {
+		t.Errorf("field At: %+v", s.Fields[1])
+	}
+	if s.Size != 72 || s.Optimal().Size != 64 {
+		t.Errorf("size %d, optimal %d, want 72 and 64", s.Size, s.Optimal().Size)
+	}
+
+	var b strings.Builder
+	s.Write(&b)
+	if !strings.Contains(b.String(), "reordered as At, Tags, ID, Seen, Retries: size 64, saves 8 bytes") {
+		t.Errorf("Write =\n%s", b.String())
+	}
+}
+
+func TestLoadGeneric(t *testing.T) {
+	dir := t.TempDir()
+	src := `package pairs
+
+type Pair[K comparable, V any] struct {
+	Key   K
+	Value V
+}
+
+type Entry struct {
+	Pair[string, int]
+	Hits uint8
+}
+`
+	if err := os.WriteFile(filepath.Join(dir, "pairs.go"), []byte(src), 0666); err != nil {
+		t.Fatal(err)
+	}
+	structs, err := Load(dir, types.SizesFor("gc", "amd64"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(structs) != 1 || structs[0].Name != "pairs.Entry" {
+		t.Fatalf("loaded %v, want pairs.Entry only", structs)
+	}
+	if s := structs[0]; s.Size != 32 || s.Fields[0].Type != "Pair[string, int]" {
+		t.Errorf("pairs.Entry: size %d, fields %+v", s.Size, s.Fields)
+	}
+}
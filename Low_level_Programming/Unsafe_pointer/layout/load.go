@@ -0,0 +1,197 @@
+package layout
+
+import (
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FromTypes returns the layout of the struct type t named name, with the sizes of
+// the target architecture, as types.SizesFor("gc", "amd64")
+func FromTypes(name string, t *types.Struct, sizes types.Sizes, qf types.Qualifier) *Struct {
+	fields := make([]*types.Var, t.NumFields())
+	for i := range fields {
+		fields[i] = t.Field(i)
+	}
+	offsets := sizes.Offsetsof(fields)
+	s := &Struct{Name: name, Size: sizes.Sizeof(t), Align: sizes.Alignof(t)}
+	for i, f := range fields {
+		s.Fields = append(s.Fields, Field{
+			Name:   f.Name(),
+			Type:   types.TypeString(f.Type(), qf),
+			Offset: offsets[i],
+			Size:   sizes.Sizeof(f.Type()),
+			Align:  sizes.Alignof(f.Type()),
+		})
+	}
+	s.pad()
+	return s
+}
+
+// Load type-checks the package in dir and returns the layout of each of its named
+// struct types, in the order of their names. Generic types are left out, as their
+// layout depends on the type arguments. Packages it imports are read from source.
+func Load(dir string, sizes types.Sizes) ([]*Struct, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	l := newLoader(dir, sizes)
+	pkgs, err := l.check(dir)
+	if err != nil {
+		return nil, err
+	}
+	var list []*Struct
+	for _, pkg := range pkgs {
+		qf := types.RelativeTo(pkg)
+		for _, n := range pkg.Scope().Names() {
+			tn, ok := pkg.Scope().Lookup(n).(*types.TypeName)
+			if !ok {
+				continue
+			}
+			if named, ok := tn.Type().(*types.Named); ok && named.TypeParams().Len() > 0 {
+				continue
+			}
+			if st, ok := tn.Type().Underlying().(*types.Struct); ok {
+				s := FromTypes(pkg.Name()+"."+n, st, sizes, qf)
+				s.Pos = l.fset.Position(tn.Pos()).String()
+				list = append(list, s)
+			}
+		}
+	}
+	return list, nil
+}
+
+// loader type-checks packages from source. go/build takes import paths without a
+// dot, as the module paths of this repository, for standard ones, so the packages
+// of the module of the loaded directory are found here and any other by go/build.
+type loader struct {
+	fset            *token.FileSet
+	sizes           types.Sizes
+	modPath, modDir string
+	other           types.ImporterFrom
+	pkgs            map[string]*types.Package
+}
+
+func newLoader(dir string, sizes types.Sizes) *loader {
+	fset := token.NewFileSet()
+	l := &loader{
+		fset:  fset,
+		sizes: sizes,
+		other: importer.ForCompiler(fset, "source", nil).(types.ImporterFrom),
+		pkgs:  make(map[string]*types.Package),
+	}
+	l.modPath, l.modDir = findModule(dir)
+	return l
+}
+
+// findModule returns the path and directory of the module holding dir, if any
+func findModule(dir string) (string, string) {
+	for {
+		if data, err := os.ReadFile(filepath.Join(dir, "go.mod")); err == nil {
+			for _, line := range strings.Split(string(data), "\n") {
+				if f := strings.Fields(line); len(f) == 2 && f[0] == "module" {
+					return strings.Trim(f[1], `"`), dir
+				}
+			}
+			return "", ""
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", ""
+		}
+		dir = parent
+	}
+}
+
+// check parses and type-checks the packages of dir, but its tests
+func (l *loader) check(dir string) ([]*types.Package, error) {
+	parsed, err := parser.ParseDir(l.fset, dir, func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, 0)
+	if err != nil {
+		return nil, err
+	}
+	var pkgs []*types.Package
+	for name, p := range parsed {
+		var files []*ast.File
+		for _, f := range p.Files {
+			files = append(files, f)
+		}
+		path := name
+		if rel, err := filepath.Rel(l.modDir, dir); err == nil && l.modPath != "" && name != "main" {
+			path = filepath.ToSlash(filepath.Join(l.modPath, rel))
+		}
+		conf := types.Config{Importer: l, Sizes: l.sizes}
+		pkg, err := conf.Check(path, l.fset, files, nil)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", dir, err)
+		}
+		pkgs = append(pkgs, pkg)
+	}
+	return pkgs, nil
+}
+
+func (l *loader) Import(path string) (*types.Package, error) {
+	return l.ImportFrom(path, "", 0)
+}
+
+func (l *loader) ImportFrom(path, dir string, mode types.ImportMode) (*types.Package, error) {
+	if l.modPath == "" || (path != l.modPath && !strings.HasPrefix(path, l.modPath+"/")) {
+		return l.other.ImportFrom(path, dir, mode)
+	}
+	if pkg, ok := l.pkgs[path]; ok {
+		return pkg, nil
+	}
+	pkgs, err := l.check(filepath.Join(l.modDir, filepath.FromSlash(strings.TrimPrefix(path, l.modPath))))
+	if err != nil {
+		return nil, err
+	}
+	for _, pkg := range pkgs {
+		if pkg.Name() != "main" {
+			l.pkgs[path] = pkg
+			return pkg, nil
+		}
+	}
+	return nil, fmt.Errorf("no package to import in %s", path)
+}
+
+// LoadTree calls Load for root and every directory below it holding Go files,
+// skipping testdata, vendor and hidden directories. A package that does not load
+// is left out, and its error is returned along with the layouts of the others.
+func LoadTree(root string, sizes types.Sizes) ([]*Struct, error) {
+	var list []*Struct
+	var failed []string
+	err := filepath.Walk(root, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !fi.IsDir() {
+			return nil
+		}
+		base := fi.Name()
+		if path != root && (base == "testdata" || base == "vendor" || strings.HasPrefix(base, ".")) {
+			return filepath.SkipDir
+		}
+		if matches, _ := filepath.Glob(filepath.Join(path, "*.go")); len(matches) == 0 {
+			return nil
+		}
+		structs, err := Load(path, sizes)
+		if err != nil {
+			failed = append(failed, err.Error())
+			return nil
+		}
+		list = append(list, structs...)
+		return nil
+	})
+	if err == nil && failed != nil {
+		err = fmt.Errorf("%s", strings.Join(failed, "\n"))
+	}
+	return list, err
+}
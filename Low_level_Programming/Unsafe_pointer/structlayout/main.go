@@ -0,0 +1,75 @@
+// Structlayout prints the memory layout of the struct types of Go packages, and the
+// field order that would make them smaller.
+//
+//	structlayout [-arch amd64] [-waste] dir|dir/... [Type...]
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/types"
+	"os"
+	"runtime"
+	"strings"
+
+	"unsafe_packages/layout"
+)
+
+func main() {
+	arch := flag.String("arch", runtime.GOARCH, "target architecture of the sizes")
+	waste := flag.Bool("waste", false, "only the structs that a new order would shrink")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: structlayout [flags] dir|dir/... [Type...]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	sizes := types.SizesFor("gc", *arch)
+	if sizes == nil {
+		fmt.Fprintf(os.Stderr, "structlayout: unknown architecture %s\n", *arch)
+		os.Exit(2)
+	}
+
+	dir := flag.Arg(0)
+	var structs []*layout.Struct
+	var err error
+	if root := strings.TrimSuffix(dir, "/..."); root != dir {
+		structs, err = layout.LoadTree(root, sizes)
+	} else {
+		structs, err = layout.Load(dir, sizes)
+	}
+	status := 0
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "structlayout: %v\n", err)
+		if structs == nil {
+			os.Exit(1)
+		}
+		status = 1
+	}
+
+	names := make(map[string]bool)
+	for _, name := range flag.Args()[1:] {
+		names[name] = true
+	}
+	var saved int64
+	for _, s := range structs {
+		if len(names) > 0 && !names[s.Name] && !names[s.Name[strings.LastIndex(s.Name, ".")+1:]] {
+			continue
+		}
+		saving := s.Size - s.Optimal().Size
+		if *waste && saving == 0 {
+			continue
+		}
+		saved += saving
+		if err := s.Write(os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "structlayout: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println()
+	}
+	fmt.Printf("%d bytes could be saved by reordering fields\n", saved)
+	os.Exit(status)
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+/*
+	Unpack reads the parameters of the query string and of the request body, whose
+	decoder is chosen by the Content-Type header:
+
+	application/x-www-form-urlencoded  l=golang&max=10
+	multipart/form-data                 values and uploaded files
+	application/json                    {"l": ["golang"], "max": 10, "addr": {"city": "Havana"}}
+
+	The keys of a JSON object are the same http tag names. Nested objects give the
+	dotted names of nested structs, arrays give repeated values and null is ignored.
+
+	When a name appears both in the query and in the body, Precedence decides which
+	values are kept.
+*/
+
+// Precedence between the query string and the body for a repeated name
+type Precedence int
+
+// Precedences
+const (
+	BodyFirst  Precedence = iota // the body replaces the query values
+	QueryFirst                   // the query replaces the body values
+	Merge                        // query values followed by body values
+)
+
+// ErrUnsupportedMediaType is returned for a body whose Content-Type has no decoder
+var ErrUnsupportedMediaType = errors.New("unsupported media type")
+
+// Binder reads the parameters of a request with the given limits
+type Binder struct {
+	MaxBodyBytes int64 // size limit of any request body
+	MaxMemory    int64 // bytes of a multipart form kept in memory, the rest go to disk
+	Precedence   Precedence
+}
+
+// DefaultBinder is used by Unpack
+var DefaultBinder = &Binder{
+	MaxBodyBytes: 10 << 20,
+	MaxMemory:    1 << 20,
+	Precedence:   BodyFirst,
+}
+
+// Unpack populates the fields of the struct pointed to by ptr from the HTTP
+// request parameters in req
+func Unpack(req *http.Request, ptr interface{}) error {
+	return DefaultBinder.Unpack(req, ptr)
+}
+
+// Unpack method
+func (b *Binder) Unpack(req *http.Request, ptr interface{}) error {
+	body, files, err := b.readBody(req)
+	if err != nil {
+		return err
+	}
+	return unpackValues(b.merge(req.URL.Query(), body), files, ptr)
+}
+
+// readBody decodes the parameters of the request body, if any
+func (b *Binder) readBody(req *http.Request) (url.Values, map[string][]*multipart.FileHeader, error) {
+	contentType := req.Header.Get("Content-Type")
+	if req.Body == nil || req.Body == http.NoBody || contentType == "" {
+		return nil, nil, nil
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrUnsupportedMediaType, err)
+	}
+	req.Body = http.MaxBytesReader(nil, req.Body, b.MaxBodyBytes)
+
+	switch {
+	case mediaType == "application/x-www-form-urlencoded":
+		data, err := io.ReadAll(req.Body)
+		if err != nil {
+			return nil, nil, err
+		}
+		values, err := url.ParseQuery(string(data))
+		if err != nil {
+			return nil, nil, bodyError(err)
+		}
+		return values, nil, nil
+	case mediaType == "multipart/form-data":
+		if err := req.ParseMultipartForm(b.MaxMemory); err != nil {
+			return nil, nil, bodyError(err)
+		}
+		return req.MultipartForm.Value, req.MultipartForm.File, nil
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		dec := json.NewDecoder(req.Body)
+		dec.UseNumber()
+		var object map[string]interface{}
+		if err := dec.Decode(&object); err != nil {
+			return nil, nil, bodyError(err)
+		}
+		values := make(url.Values)
+		if err := flatten(values, "", object); err != nil {
+			return nil, nil, err
+		}
+		return values, nil, nil
+	}
+	return nil, nil, fmt.Errorf("%w %s", ErrUnsupportedMediaType, mediaType)
+}
+
+// bodyError reports a body that could not be parsed as a client error, unless it
+// failed for being too large
+func bodyError(err error) error {
+	var maxBytes *http.MaxBytesError
+	if errors.As(err, &maxBytes) {
+		return err
+	}
+	return &FieldError{"body", err}
+}
+
+// flatten stores the JSON value x in values under name, with the keys of objects
+// added to the name after a dot
+func flatten(values url.Values, name string, x interface{}) error {
+	switch x := x.(type) {
+	case nil:
+	case map[string]interface{}:
+		for key, elem := range x {
+			if name != "" {
+				key = name + "." + key
+			}
+			if err := flatten(values, key, elem); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, elem := range x {
+			switch elem.(type) {
+			case map[string]interface{}, []interface{}:
+				return &FieldError{name, fmt.Errorf("arrays may only hold plain values")}
+			}
+			if err := flatten(values, name, elem); err != nil {
+				return err
+			}
+		}
+	case string:
+		values.Add(name, x)
+	case json.Number:
+		values.Add(name, x.String())
+	case bool:
+		values.Add(name, fmt.Sprint(x))
+	}
+	return nil
+}
+
+// merge combines the query and body values following the precedence of b
+func (b *Binder) merge(query, body url.Values) url.Values {
+	form := make(url.Values)
+	first, second := query, body
+	if b.Precedence == QueryFirst {
+		first, second = body, query
+	}
+	for name, values := range first {
+		form[name] = append(form[name], values...)
+	}
+	for name, values := range second {
+		if b.Precedence == Merge {
+			form[name] = append(form[name], values...)
+		} else {
+			form[name] = values
+		}
+	}
+	return form
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type upload struct {
+	Labels []string                `http:"l"`
+	Max    int                     `http:"max"`
+	City   string                  `http:"addr.city"`
+	Doc    *multipart.FileHeader   `http:"doc" validate:"required"`
+	Extra  []*multipart.FileHeader `http:"extra"`
+}
+
+type searchForm struct {
+	Labels []string `http:"l"`
+	Max    int      `http:"max"`
+	Addr   struct {
+		City string `http:"city"`
+	} `http:"addr"`
+}
+
+func TestUnpackBodies(t *testing.T) {
+	for _, test := range []struct {
+		contentType, body string
+	}{
+		{"application/x-www-form-urlencoded", "l=golang&l=programming&max=20&addr.city=Havana"},
+		{"application/json", `{"l": ["golang", "programming"], "max": 20, "addr": {"city": "Havana"}}`},
+		{"application/problem+json; charset=utf-8", `{"l": ["golang", "programming"], "max": "20", "addr.city": "Havana", "x": null}`},
+	} {
+		req := httptest.NewRequest("POST", "/search", strings.NewReader(test.body))
+		req.Header.Set("Content-Type", test.contentType)
+		var got searchForm
+		if err := Unpack(req, &got); err != nil {
+			t.Errorf("%s: %v", test.contentType, err)
+			continue
+		}
+		if !reflect.DeepEqual(got.Labels, []string{"golang", "programming"}) ||
+			got.Max != 20 || got.Addr.City != "Havana" {
+			t.Errorf("%s: Unpack = %+v", test.contentType, got)
+		}
+	}
+}
+
+func TestUnpackMultipart(t *testing.T) {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	w.WriteField("l", "golang")
+	w.WriteField("addr.city", "Havana")
+	for _, name := range []string{"doc", "extra", "extra"} {
+		f, _ := w.CreateFormFile(name, name+".txt")
+		f.Write([]byte("contents of " + name))
+	}
+	w.Close()
+
+	req := httptest.NewRequest("POST", "/upload?max=5", &body)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	var got upload
+	if err := Unpack(req, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Max != 5 || got.City != "Havana" || got.Doc == nil || got.Doc.Filename != "doc.txt" ||
+		len(got.Extra) != 2 {
+		t.Errorf("Unpack = %+v", got)
+	}
+}
+
+func TestUnpackPrecedence(t *testing.T) {
+	for _, test := range []struct {
+		precedence Precedence
+		want       []string
+	}{
+		{BodyFirst, []string{"body"}},
+		{QueryFirst, []string{"query"}},
+		{Merge, []string{"query", "body"}},
+	} {
+		req := httptest.NewRequest("POST", "/search?l=query", strings.NewReader("l=body"))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		b := &Binder{MaxBodyBytes: 1 << 10, Precedence: test.precedence}
+		var got searchForm
+		if err := b.Unpack(req, &got); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got.Labels, test.want) {
+			t.Errorf("precedence %d: labels = %v, want %v", test.precedence, got.Labels, test.want)
+		}
+	}
+}
+
+func TestUnpackBodyErrors(t *testing.T) {
+	b := &Binder{MaxBodyBytes: 16}
+	req := httptest.NewRequest("POST", "/search", strings.NewReader(`{"l": ["a very long label"]}`))
+	req.Header.Set("Content-Type", "application/json")
+	var maxErr *http.MaxBytesError
+	if err := b.Unpack(req, &searchForm{}); !errors.As(err, &maxErr) {
+		t.Errorf("oversized body: got %v, want *http.MaxBytesError", err)
+	}
+
+	req = httptest.NewRequest("POST", "/search", strings.NewReader("<l>go</l>"))
+	req.Header.Set("Content-Type", "text/xml")
+	if err := Unpack(req, &searchForm{}); !errors.Is(err, ErrUnsupportedMediaType) {
+		t.Errorf("xml body: got %v, want ErrUnsupportedMediaType", err)
+	}
+
+	req = httptest.NewRequest("POST", "/search", strings.NewReader(`{"l": [{"a": 1}]}`))
+	req.Header.Set("Content-Type", "application/json")
+	if err := Unpack(req, &searchForm{}); err == nil {
+		t.Errorf("array of objects: got no error")
+	}
+
+	for _, test := range []struct {
+		contentType, body string
+	}{
+		{"application/json", `{"l": ["go"`},
+		{"application/json", `["go"]`},
+		{"application/x-www-form-urlencoded", "l=%zz"},
+	} {
+		req := httptest.NewRequest("POST", "/search", strings.NewReader(test.body))
+		req.Header.Set("Content-Type", test.contentType)
+		var fieldErr *FieldError
+		if err := Unpack(req, &searchForm{}); !errors.As(err, &fieldErr) || fieldErr.Field != "body" {
+			t.Errorf("%s body %q: got %v, want a *FieldError for the body", test.contentType, test.body, err)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+/*
+	Adapt turns a function of the form
+
+	func(ctx context.Context, in SearchParams) (SearchResult, error)
+
+	into an http.Handler. The argument in, a struct or a pointer to one, gets its
+	Defaults method called if it has one, and is then bound from the request with
+	Unpack and its validate tags. The result is written as JSON, or through Template
+	when the client prefers text/html. Errors become status codes:
+
+	FieldErrors, *FieldError   400 Bad Request, also for a body that does not parse
+	*http.MaxBytesError        413 Request Entity Too Large
+	ErrUnsupportedMediaType    415 Unsupported Media Type
+	an error with StatusCode() that status
+	context.DeadlineExceeded   504 Gateway Timeout
+	anything else              500 Internal Server Error
+*/
+
+// Adapter is the http.Handler made from a function by Adapt
+type Adapter struct {
+	fn       reflect.Value
+	in       reflect.Type
+	Binder   *Binder            // nil means DefaultBinder
+	Template *template.Template // renders results for text/html, optional
+}
+
+// StatusError is an error carrying the HTTP status to respond with
+type StatusError struct {
+	Code int
+	Err  error
+}
+
+func (e *StatusError) Error() string   { return e.Err.Error() }
+func (e *StatusError) Unwrap() error   { return e.Err }
+func (e *StatusError) StatusCode() int { return e.Code }
+
+// Errorf returns a StatusError with a formatted message
+func Errorf(code int, format string, args ...interface{}) error {
+	return &StatusError{code, fmt.Errorf(format, args...)}
+}
+
+var (
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// Adapt checks the signature of fn and returns its handler
+func Adapt(fn interface{}) (*Adapter, error) {
+	v := reflect.ValueOf(fn)
+	if !v.IsValid() {
+		return nil, fmt.Errorf("adapt: nil function")
+	}
+	t := v.Type()
+	if t.Kind() != reflect.Func || t.NumIn() != 2 || t.NumOut() != 2 || t.IsVariadic() {
+		return nil, fmt.Errorf("adapt: want func(context.Context, In) (Out, error), got %s", t)
+	}
+	if t.In(0) != contextType {
+		return nil, fmt.Errorf("adapt: first argument of %s is not context.Context", t)
+	}
+	in := t.In(1)
+	if in.Kind() == reflect.Ptr {
+		in = in.Elem()
+	}
+	if in.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("adapt: second argument of %s is not a struct", t)
+	}
+	if t.Out(1) != errorType {
+		return nil, fmt.Errorf("adapt: second result of %s is not error", t)
+	}
+	return &Adapter{fn: v, in: in}, nil
+}
+
+// MustAdapt is like Adapt but panics on a bad signature
+func MustAdapt(fn interface{}) *Adapter {
+	a, err := Adapt(fn)
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
+
+func (a *Adapter) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	binder := a.Binder
+	if binder == nil {
+		binder = DefaultBinder
+	}
+	in := reflect.New(a.in)
+	if d, ok := in.Interface().(interface{ Defaults() }); ok {
+		d.Defaults()
+	}
+	if err := binder.Unpack(req, in.Interface()); err != nil {
+		a.writeError(resp, req, err)
+		return
+	}
+	arg := in
+	if a.fn.Type().In(1).Kind() != reflect.Ptr {
+		arg = in.Elem()
+	}
+	out := a.fn.Call([]reflect.Value{reflect.ValueOf(req.Context()), arg})
+	if err, _ := out[1].Interface().(error); err != nil {
+		a.writeError(resp, req, err)
+		return
+	}
+	a.write(resp, req, http.StatusOK, out[0].Interface())
+}
+
+func (a *Adapter) write(resp http.ResponseWriter, req *http.Request, code int, result interface{}) {
+	if a.Template != nil && prefersHTML(req.Header.Get("Accept")) {
+		resp.Header().Set("Content-Type", "text/html; charset=utf-8")
+		resp.WriteHeader(code)
+		if err := a.Template.Execute(resp, result); err != nil {
+			fmt.Fprintf(resp, "<p>template error: %s</p>", template.HTMLEscapeString(err.Error()))
+		}
+		return
+	}
+	resp.Header().Set("Content-Type", "application/json")
+	resp.WriteHeader(code)
+	json.NewEncoder(resp).Encode(result)
+}
+
+// errorBody is the JSON document written for an error
+type errorBody struct {
+	Error  string            `json:"error"`
+	Fields map[string]string `json:"fields,omitempty"`
+}
+
+func (a *Adapter) writeError(resp http.ResponseWriter, req *http.Request, err error) {
+	code := statusOf(err)
+	if a.Template != nil && prefersHTML(req.Header.Get("Accept")) {
+		msg := err.Error()
+		if code >= http.StatusInternalServerError {
+			msg = http.StatusText(code) // the error may hold internal details
+		}
+		http.Error(resp, msg, code)
+		return
+	}
+	body := errorBody{Error: http.StatusText(code)}
+	var fields FieldErrors
+	if errors.As(err, &fields) {
+		body.Fields = make(map[string]string)
+		for _, f := range fields {
+			body.Fields[f.Field] = f.Err.Error()
+		}
+	} else if code < http.StatusInternalServerError {
+		body.Error = err.Error()
+	}
+	resp.Header().Set("Content-Type", "application/json")
+	resp.WriteHeader(code)
+	json.NewEncoder(resp).Encode(body)
+}
+
+// statusOf maps an error to an HTTP status code
+func statusOf(err error) int {
+	var coded interface{ StatusCode() int }
+	var fields FieldErrors
+	var field *FieldError
+	var maxBytes *http.MaxBytesError
+	switch {
+	case errors.As(err, &coded):
+		return coded.StatusCode()
+	case errors.As(err, &maxBytes):
+		return http.StatusRequestEntityTooLarge
+	case errors.As(err, &fields), errors.As(err, &field):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrUnsupportedMediaType):
+		return http.StatusUnsupportedMediaType
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusInternalServerError
+}
+
+// prefersHTML reports whether the Accept header ranks text/html above JSON
+func prefersHTML(accept string) bool {
+	html, jsonQ := -1.0, -1.0
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if s, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(s, 64); err != nil {
+				continue
+			}
+		}
+		switch mediaType {
+		case "text/html":
+			if q > html {
+				html = q
+			}
+		case "application/json", "*/*":
+			if q > jsonQ {
+				jsonQ = q
+			}
+		}
+	}
+	return html > 0 && html > jsonQ
+}
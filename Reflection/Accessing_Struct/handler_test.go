@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAdapter(t *testing.T) {
+	notFound := func(ctx context.Context, in *SearchParams) (*SearchResult, error) {
+		if len(in.Labels) == 0 {
+			return nil, Errorf(http.StatusNotFound, "nothing to search")
+		}
+		if in.Labels[0] == "slow" {
+			return nil, context.DeadlineExceeded
+		}
+		if in.Labels[0] == "boom" {
+			return nil, errors.New("database password is hunter2")
+		}
+		return &SearchResult{Search: *in}, nil
+	}
+	for _, test := range []struct {
+		fn               interface{}
+		target, accept   string
+		code             int
+		contains, absent string
+	}{
+		{search, "/search?l=golang", "", 200, `{"Search":{"Labels":["golang"],"MaxResult":10,"Exact":false}}`, ""},
+		{search, "/search?l=golang&max=0&y=1", "", 400, `"max":"must be at least 1"`, ""},
+		{search, "/search?max=0", "text/html", 400, "max: must be at least 1", ""},
+		{notFound, "/search", "", 404, "nothing to search", ""},
+		{notFound, "/search?l=slow", "", 504, "Gateway Timeout", ""},
+		{notFound, "/search?l=boom", "", 500, "Internal Server Error", "hunter2"},
+		{notFound, "/search?l=boom", "text/html", 500, "Internal Server Error", "hunter2"},
+		{notFound, "/search?l=golang", "text/html,*/*;q=0.8", 200, "<p>Search: golang max=10", ""},
+		{notFound, "/search?l=golang", "text/html;q=0.5,application/json", 200, `"Labels":["golang"]`, ""},
+	} {
+		handler := MustAdapt(test.fn)
+		handler.Template = searchPage
+		req := httptest.NewRequest("GET", test.target, nil)
+		req.Header.Set("Accept", test.accept)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		body := rec.Body.String()
+		if rec.Code != test.code || !strings.Contains(body, test.contains) ||
+			test.absent != "" && strings.Contains(body, test.absent) {
+			t.Errorf("GET %s (Accept %q) = %d %s, want %d containing %s",
+				test.target, test.accept, rec.Code, body, test.code, test.contains)
+		}
+	}
+}
+
+func TestAdapterBodies(t *testing.T) {
+	for _, test := range []struct {
+		contentType, body string
+		code              int
+	}{
+		{"application/json", `{"l": ["golang"]}`, 200},
+		{"application/json", `{"l": ["golang"`, 400},
+		{"application/json", `["golang"]`, 400},
+		{"application/x-www-form-urlencoded", "l=%zz", 400},
+		{"text/xml", "<l>golang</l>", 415},
+	} {
+		req := httptest.NewRequest("POST", "/search", strings.NewReader(test.body))
+		req.Header.Set("Content-Type", test.contentType)
+		rec := httptest.NewRecorder()
+		MustAdapt(search).ServeHTTP(rec, req)
+		if rec.Code != test.code {
+			t.Errorf("POST %s %.20q = %d %s, want %d", test.contentType, test.body, rec.Code, rec.Body, test.code)
+		}
+	}
+}
+
+func TestAdaptSignature(t *testing.T) {
+	for _, fn := range []interface{}{
+		nil,
+		42,
+		func(in SearchParams) (SearchResult, error) { return SearchResult{}, nil },
+		func(ctx context.Context, in string) (SearchResult, error) { return SearchResult{}, nil },
+		func(ctx context.Context, in SearchParams) (SearchResult, string) { return SearchResult{}, "" },
+	} {
+		if _, err := Adapt(fn); err == nil {
+			t.Errorf("Adapt(%T) succeeded, want error", fn)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Ejercicio 12.11
+// Input Search: {Labels:[golang programming] MaxResults:10 Exact:true} x->Exact, l->Labels, max->MaxResult
+// Ouput 'http://localhost:12345/search?l=golang&l=programming&max=10&x=true'
+
+/*
+	Pack is the inverse of Unpack: it reads the same http tags and writes every field
+	as a query parameter of base. Slices become repeated parameters, nil pointers are
+	left out, and so is any zero value of a field tagged omitempty:
+
+	Exact bool `http:"x,omitempty"`
+
+	The query of base is replaced, since Unpack rejects parameters with no field.
+	An empty slice writes no parameter and unpacks as nil, and a time keeps its
+	instant and offset but not the name of its location.
+*/
+
+var textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+// Pack returns a copy of base with the fields of the struct pointed to by ptr as
+// its query
+func Pack(base *url.URL, ptr interface{}) (*url.URL, error) {
+	v := reflect.ValueOf(ptr)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("pack needs a struct or a pointer to one, got %T", ptr)
+	}
+	if !v.CanAddr() {
+		// methods with pointer receivers, like MarshalText, need an addressable copy
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		v = c
+	}
+	query := make(url.Values)
+	var errs FieldErrors
+	for _, p := range params(v) {
+		if _, opts := paramName(p.info); hasOption(opts, "omitempty") && p.value.IsZero() ||
+			isFile(p.value.Type()) || p.ptrs != nil {
+			continue
+		}
+		values, err := format(p.value)
+		if err != nil {
+			errs = append(errs, &FieldError{p.name, err})
+			continue
+		}
+		for _, value := range values {
+			query.Add(p.name, value)
+		}
+	}
+	if errs != nil {
+		return nil, errs
+	}
+	u := *base
+	u.RawQuery = query.Encode()
+	return &u, nil
+}
+
+func hasOption(opts, option string) bool {
+	for opts != "" {
+		var opt string
+		opt, opts, _ = strings.Cut(opts, ",")
+		if opt == option {
+			return true
+		}
+	}
+	return false
+}
+
+// format returns the parameter values of v, one per element of a slice
+func format(v reflect.Value) ([]string, error) {
+	if v.Kind() == reflect.Slice && !isText(v.Type()) {
+		var values []string
+		for i := 0; i < v.Len(); i++ {
+			value, err := formatParam(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value...)
+		}
+		return values, nil
+	}
+	return formatParam(v)
+}
+
+// formatParam returns the text of a single value, as populate parses it
+func formatParam(v reflect.Value) ([]string, error) {
+	switch {
+	case v.Type() == timeType:
+		return []string{v.Interface().(time.Time).Format(time.RFC3339Nano)}, nil
+	case v.Type() == durationType:
+		return []string{time.Duration(v.Int()).String()}, nil
+	case v.Type().Implements(textMarshalerType):
+		if v.Kind() == reflect.Ptr && v.IsNil() {
+			return nil, nil
+		}
+		text, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		return []string{string(text)}, err
+	case v.CanAddr() && reflect.PointerTo(v.Type()).Implements(textMarshalerType):
+		text, err := v.Addr().Interface().(encoding.TextMarshaler).MarshalText()
+		return []string{string(text)}, err
+	}
+
+	var s string
+	switch v.Kind() {
+	case reflect.String:
+		s = v.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		s = strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Uintptr:
+		s = strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		s = strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits())
+	case reflect.Complex64, reflect.Complex128:
+		s = strconv.FormatComplex(v.Complex(), 'g', -1, v.Type().Bits())
+	case reflect.Bool:
+		s = strconv.FormatBool(v.Bool())
+	case reflect.Ptr:
+		if v.IsNil() {
+			return nil, nil
+		}
+		return formatParam(v.Elem())
+	default:
+		return nil, fmt.Errorf("unsupported kind %s", v.Type())
+	}
+	return []string{s}, nil
+}
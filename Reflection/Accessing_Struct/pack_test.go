@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type priority int
+
+func (p *priority) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "low":
+		*p = 1
+	case "high":
+		*p = 2
+	default:
+		return fmt.Errorf("unknown priority %q", text)
+	}
+	return nil
+}
+
+func (p priority) MarshalText() ([]byte, error) {
+	switch p {
+	case 1:
+		return []byte("low"), nil
+	case 2:
+		return []byte("high"), nil
+	}
+	return nil, fmt.Errorf("unknown priority %d", int(p))
+}
+
+type packed struct {
+	Labels   []string      `http:"l"`
+	Max      int8          `http:"max"`
+	Ratio    float32       `http:"ratio"`
+	Exact    bool          `http:"x"`
+	Since    time.Time     `http:"since"`
+	Timeout  time.Duration `http:"timeout"`
+	Priority priority      `http:"p,omitempty"`
+	Mail     string        `http:"mail"`
+	Limit    *int          `http:"limit"`
+	Addr     address       `http:"addr"`
+}
+
+func TestPackUnpack(t *testing.T) {
+	limit := 3
+	base, _ := url.Parse("http://localhost:12345/search?lang=es")
+	for _, x := range []packed{
+		{Max: 1, Addr: address{City: "Havana"}},
+		{
+			Labels:   []string{"go", "c", "rust"},
+			Max:      100,
+			Ratio:    1.0 / 3,
+			Exact:    true,
+			Since:    time.Date(2023, 3, 1, 12, 30, 0, 5, time.UTC),
+			Timeout:  90 * time.Millisecond,
+			Priority: 1,
+			Mail:     "gopher+tag@example.com",
+			Limit:    &limit,
+			Addr:     address{"La Habana & Centro", 65535},
+		},
+		{
+			Labels: []string{""},
+			Since:  time.Date(2023, 3, 1, 7, 30, 0, 0, time.FixedZone("", -5*60*60)),
+			Addr:   address{City: "Matanzas"},
+		},
+	} {
+		u, err := Pack(base, x)
+		if err != nil {
+			t.Errorf("Pack(%+v): %v", x, err)
+			continue
+		}
+		if u.Path != "/search" || base.RawQuery != "lang=es" {
+			t.Errorf("Pack lost the path or modified base: %s", u)
+		}
+		var y packed
+		if err := Unpack(httptest.NewRequest("GET", u.String(), nil), &y); err != nil {
+			t.Errorf("Unpack(%s): %v", u, err)
+			continue
+		}
+		if !reflect.DeepEqual(x, y) {
+			t.Errorf("Unpack(Pack(%+v)) = %+v\nurl %s", x, y, u)
+		}
+	}
+}
+
+func TestPackLossy(t *testing.T) {
+	havana, err := time.LoadLocation("America/Havana")
+	if err != nil {
+		t.Skip(err)
+	}
+	base, _ := url.Parse("http://localhost:12345/search")
+	x := packed{
+		Labels: []string{},
+		Since:  time.Date(2023, 3, 1, 7, 30, 0, 0, havana),
+		Addr:   address{City: "Havana"},
+	}
+	u, err := Pack(base, x)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var y packed
+	if err := Unpack(httptest.NewRequest("GET", u.String(), nil), &y); err != nil {
+		t.Fatalf("Unpack(%s): %v", u, err)
+	}
+	// an empty slice comes back nil, and a time with the offset of its location
+	if y.Labels != nil {
+		t.Errorf("Labels = %#v, want nil", y.Labels)
+	}
+	if !y.Since.Equal(x.Since) {
+		t.Errorf("Since = %v, want %v", y.Since, x.Since)
+	}
+	if _, offset := y.Since.Zone(); offset != -5*60*60 {
+		t.Errorf("Since has offset %d, want -5h", offset)
+	}
+}
+
+func TestPackOmitEmpty(t *testing.T) {
+	base, _ := url.Parse("http://localhost:12345/search")
+	data := struct {
+		Labels []string `http:"l"`
+		Max    int      `http:"max"`
+		Exact  bool     `http:"x"`
+		Page   int      `http:"page,omitempty"`
+		Sort   string   `http:"-"`
+	}{Labels: []string{"golang", "c++"}, Sort: "date"}
+	u, err := Pack(base, &data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "http://localhost:12345/search?l=golang&l=c%2B%2B&max=0&x=false"
+	if u.String() != want {
+		t.Errorf("Pack = %s, want %s", u, want)
+	}
+}
@@ -0,0 +1,491 @@
+package main
+
+import (
+	"encoding"
+	"fmt"
+	"mime/multipart"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+	Unpack binds request parameters to the fields of a struct. The parameter name of a
+	field is its http tag, or its name in lower case. Fields of struct type are
+	unpacked too, their parameter names are prefixed with the name of the
+	enclosing field and a dot. So are pointers to structs, allocated and validated
+	only when one of their parameters is given; a pointer to an enclosing struct
+	type is skipped:
+
+	type Search struct {
+		Labels []string `http:"l"`
+		Addr   struct {
+			City string `http:"city" validate:"required"`
+		} `http:"addr"`
+	}
+
+	binds l=golang&l=programming&addr.city=Havana
+
+	Ejercicio 12.12: the validate tag lists the requirements of a field, separated by
+	commas:
+
+	required     the parameter must be present
+	min=n, max=n bounds of a number, or of the length of a string or slice
+	oneof=a|b    the value must be one of the listed ones
+	email        the value must be a plain e-mail address
+
+	Every error is collected, so the caller can report all of them at once. Uploads of
+	a multipart form bind to fields of type *multipart.FileHeader or
+	[]*multipart.FileHeader, see bind.go for the sources of the parameters.
+*/
+
+// FieldError describes a parameter that could not be bound or is not valid
+type FieldError struct {
+	Field string // parameter name
+	Err   error
+}
+
+func (e *FieldError) Error() string { return e.Field + ": " + e.Err.Error() }
+
+// FieldErrors is the list of every FieldError found by Unpack
+type FieldErrors []*FieldError
+
+func (errs FieldErrors) Error() string {
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// param is a field of the struct bound to a parameter name
+type param struct {
+	name  string
+	value reflect.Value
+	info  reflect.StructField
+	ptrs  []pending // nil pointers on the way to the field
+}
+
+// pending is a nil pointer to a struct and the struct allocated for it
+type pending struct {
+	field, alloc reflect.Value
+}
+
+// setPointers stores the structs allocated on the way to the field of p, once it
+// is bound
+func (p param) setPointers() {
+	for _, ptr := range p.ptrs {
+		if ptr.field.IsNil() {
+			ptr.field.Set(ptr.alloc)
+		}
+	}
+}
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	fileType            = reflect.TypeOf((*multipart.FileHeader)(nil))
+)
+
+// paramName returns the parameter name of a field and the options that follow it
+// in the http tag, as in `http:"l,omitempty"`
+func paramName(info reflect.StructField) (string, string) {
+	name, opts, _ := strings.Cut(info.Tag.Get("http"), ",")
+	if name == "" {
+		name = strings.ToLower(info.Name)
+	}
+	return name, opts
+}
+
+// params returns the bindable fields of the struct v, with nested structs flattened
+func params(v reflect.Value) []param {
+	return fieldParams(v, "", nil, map[reflect.Type]bool{v.Type(): true})
+}
+
+// fieldParams returns the fields of v, below the nil pointers ptrs, skipping pointers
+// to the struct types in outer
+func fieldParams(v reflect.Value, prefix string, ptrs []pending, outer map[reflect.Type]bool) []param {
+	var list []param
+	for i := 0; i < v.NumField(); i++ {
+		info := v.Type().Field(i)
+		if !info.IsExported() || info.Tag.Get("http") == "-" {
+			continue
+		}
+		name, _ := paramName(info)
+		f := v.Field(i)
+		switch {
+		case isNested(f.Type()):
+			list = append(list, fieldParams(f, prefix+name+".", ptrs, outer)...)
+		case f.Kind() == reflect.Ptr && f.Type() != fileType && isNested(f.Type().Elem()):
+			elem := f.Type().Elem()
+			if outer[elem] {
+				continue
+			}
+			outer[elem] = true
+			if f.IsNil() {
+				alloc := reflect.New(elem)
+				below := append(ptrs[:len(ptrs):len(ptrs)], pending{f, alloc})
+				list = append(list, fieldParams(alloc.Elem(), prefix+name+".", below, outer)...)
+			} else {
+				list = append(list, fieldParams(f.Elem(), prefix+name+".", ptrs, outer)...)
+			}
+			delete(outer, elem)
+		default:
+			list = append(list, param{prefix + name, f, info, ptrs})
+		}
+	}
+	return list
+}
+
+// isNested reports whether fields of type t are unpacked field by field
+func isNested(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && t != timeType &&
+		!reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+// unpackValues populates the struct pointed to by ptr from the parameters in form
+// and the uploaded files
+func unpackValues(form url.Values, files map[string][]*multipart.FileHeader, ptr interface{}) error {
+	v := reflect.ValueOf(ptr)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("unpack needs a pointer to a struct, got %T", ptr)
+	}
+	list := params(v.Elem())
+	fields := make(map[string]param)
+	for _, p := range list {
+		fields[p.name] = p
+	}
+
+	var errs FieldErrors
+	failed := make(map[string]bool)
+	for _, name := range sortedNames(form) {
+		p, ok := fields[name]
+		if !ok {
+			errs = append(errs, &FieldError{name, fmt.Errorf("unknown parameter")})
+			continue
+		}
+		if err := bind(p.value, form[name]); err != nil {
+			errs = append(errs, &FieldError{name, err})
+			failed[name] = true
+			continue
+		}
+		p.setPointers()
+	}
+	for _, name := range sortedFiles(files) {
+		p, ok := fields[name]
+		if !ok {
+			errs = append(errs, &FieldError{name, fmt.Errorf("unknown file")})
+			continue
+		}
+		if err := bindFiles(p.value, files[name]); err != nil {
+			errs = append(errs, &FieldError{name, err})
+			failed[name] = true
+			continue
+		}
+		p.setPointers()
+	}
+	for _, p := range list {
+		if failed[p.name] || p.ptrs != nil && p.ptrs[len(p.ptrs)-1].field.IsNil() {
+			continue // bad, or below a pointer no parameter was given for
+		}
+		_, present := form[p.name]
+		if !present {
+			_, present = files[p.name]
+		}
+		if err := validate(p, present); err != nil {
+			errs = append(errs, &FieldError{p.name, err})
+		}
+	}
+	if errs != nil {
+		return errs
+	}
+	return nil
+}
+
+func sortedNames(form url.Values) []string {
+	names := make([]string, 0, len(form))
+	for name := range form {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sortedFiles(files map[string][]*multipart.FileHeader) []string {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// bind stores values in f, appending to slices and keeping the last of several
+// values for any other kind
+func bind(f reflect.Value, values []string) error {
+	for _, value := range values {
+		if f.Kind() == reflect.Slice && !isText(f.Type()) {
+			elem := reflect.New(f.Type().Elem()).Elem()
+			if err := populate(elem, value); err != nil {
+				return err
+			}
+			f.Set(reflect.Append(f, elem))
+		} else {
+			if err := populate(f, value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// bindFiles stores uploads in a *multipart.FileHeader or []*multipart.FileHeader
+func bindFiles(f reflect.Value, headers []*multipart.FileHeader) error {
+	switch f.Type() {
+	case fileType:
+		f.Set(reflect.ValueOf(headers[len(headers)-1]))
+	case reflect.SliceOf(fileType):
+		for _, h := range headers {
+			f.Set(reflect.Append(f, reflect.ValueOf(h)))
+		}
+	default:
+		return fmt.Errorf("%s can not hold a file", f.Type())
+	}
+	return nil
+}
+
+// isFile reports whether t holds uploaded files
+func isFile(t reflect.Type) bool {
+	return t == fileType || t == reflect.SliceOf(fileType)
+}
+
+// isText reports whether t parses itself from text
+func isText(t reflect.Type) bool {
+	return t == timeType || reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+// timeLayouts accepted for time.Time fields
+var timeLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04", "2006-01-02"}
+
+// parseTime parses value in the first of timeLayouts that fits
+func parseTime(value string) (time.Time, error) {
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q", value)
+}
+
+func populate(v reflect.Value, value string) error {
+	switch {
+	case v.Type() == fileType:
+		return fmt.Errorf("expects a file upload")
+	case v.Type() == timeType:
+		t, err := parseTime(value)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	case reflect.PointerTo(v.Type()).Implements(textUnmarshalerType):
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value))
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Uintptr:
+		u, err := strconv.ParseUint(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Complex64, reflect.Complex128:
+		c, err := strconv.ParseComplex(value, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetComplex(c)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Ptr:
+		elem := reflect.New(v.Type().Elem())
+		if err := populate(elem.Elem(), value); err != nil {
+			return err
+		}
+		v.Set(elem)
+	default:
+		return fmt.Errorf("unsupported kind %s", v.Type())
+	}
+	return nil
+}
+
+// validate checks the requirements of the validate tag of p
+func validate(p param, present bool) error {
+	tag := p.info.Tag.Get("validate")
+	if tag == "" {
+		return nil
+	}
+	for _, rule := range strings.Split(tag, ",") {
+		key, arg, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		var err error
+		switch key {
+		case "required":
+			if !present {
+				err = fmt.Errorf("required")
+			}
+		case "min", "max":
+			if present || !p.value.IsZero() {
+				err = checkBound(p.value, key, arg)
+			}
+		case "oneof":
+			err = forEach(p.value, func(v reflect.Value) error {
+				s := formatValue(v)
+				for _, option := range strings.Split(arg, "|") {
+					if s == option {
+						return nil
+					}
+				}
+				return fmt.Errorf("%q is not one of %s", s, strings.Replace(arg, "|", ", ", -1))
+			})
+		case "email":
+			err = forEach(p.value, func(v reflect.Value) error {
+				s := formatValue(v)
+				if a, err := mail.ParseAddress(s); err != nil || a.Address != s {
+					return fmt.Errorf("%q is not an e-mail address", s)
+				}
+				return nil
+			})
+		case "":
+		default:
+			err = fmt.Errorf("unknown validation %q", key)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// forEach applies check to v, or to each element of a slice v. Zero values were
+// not given, and are left to required.
+func forEach(v reflect.Value, check func(v reflect.Value) error) error {
+	if v.Kind() == reflect.Slice && !isText(v.Type()) {
+		for i := 0; i < v.Len(); i++ {
+			if err := check(v.Index(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if v.IsZero() {
+		return nil
+	}
+	return check(v)
+}
+
+// checkBound compares numbers, durations and times by value, and strings and
+// slices by length
+func checkBound(v reflect.Value, key, arg string) error {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	var n, bound float64
+	var err error
+	what := ""
+	switch {
+	case v.Type() == timeType:
+		return checkTime(v.Interface().(time.Time), key, arg)
+	case v.Type() == durationType:
+		var d time.Duration
+		d, err = time.ParseDuration(arg)
+		n, bound = float64(v.Int()), float64(d)
+	default:
+		bound, err = strconv.ParseFloat(arg, 64)
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			n = float64(v.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Uintptr:
+			n = float64(v.Uint())
+		case reflect.Float32, reflect.Float64:
+			n = v.Float()
+		case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+			n, what = float64(v.Len()), "length "
+		default:
+			return fmt.Errorf("%s does not apply to %s", key, v.Type())
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("bad %s=%s: %v", key, arg, err)
+	}
+	if key == "min" && n < bound {
+		return fmt.Errorf("%smust be at least %s", what, arg)
+	}
+	if key == "max" && n > bound {
+		return fmt.Errorf("%smust be at most %s", what, arg)
+	}
+	return nil
+}
+
+// checkTime compares t with the bound arg, a time written as the parameters are
+func checkTime(t time.Time, key, arg string) error {
+	bound, err := parseTime(arg)
+	if err != nil {
+		return fmt.Errorf("bad %s=%s: %v", key, arg, err)
+	}
+	if key == "min" && t.Before(bound) {
+		return fmt.Errorf("must be at least %s", arg)
+	}
+	if key == "max" && t.After(bound) {
+		return fmt.Errorf("must be at most %s", arg)
+	}
+	return nil
+}
+
+// formatValue returns the text form of a bound value
+func formatValue(v reflect.Value) string {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.String {
+		return v.String()
+	}
+	return fmt.Sprint(v.Interface())
+}
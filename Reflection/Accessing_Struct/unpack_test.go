@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type level int
+
+func (l *level) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "low":
+		*l = 1
+	case "high":
+		*l = 2
+	default:
+		return fmt.Errorf("unknown level %q", text)
+	}
+	return nil
+}
+
+type address struct {
+	City string `http:"city" validate:"required"`
+	Zip  uint16 `http:"zip"`
+}
+
+type query struct {
+	Labels  []string      `http:"l" validate:"max=3,oneof=go|c|rust"`
+	Max     int8          `http:"max" validate:"min=1,max=100"`
+	Ratio   float32       `http:"ratio"`
+	Exact   bool          `http:"x"`
+	Since   time.Time     `http:"since"`
+	Timeout time.Duration `http:"timeout" validate:"max=1m"`
+	Level   level         `http:"level"`
+	Mail    string        `http:"mail" validate:"email"`
+	Limit   *int          `http:"limit"`
+	Addr    address       `http:"addr"`
+}
+
+func TestUnpack(t *testing.T) {
+	req := httptest.NewRequest("GET", "/search?l=go&l=rust&max=20&ratio=0.5&x=true"+
+		"&since=2023-03-01&timeout=30s&level=high&mail=gopher@example.com&limit=7"+
+		"&addr.city=Havana&addr.zip=10400", nil)
+	var got query
+	if err := Unpack(req, &got); err != nil {
+		t.Fatal(err)
+	}
+	limit := 7
+	want := query{
+		Labels:  []string{"go", "rust"},
+		Max:     20,
+		Ratio:   0.5,
+		Exact:   true,
+		Since:   time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC),
+		Timeout: 30 * time.Second,
+		Level:   2,
+		Mail:    "gopher@example.com",
+		Limit:   &limit,
+		Addr:    address{"Havana", 10400},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unpack = %+v, want %+v", got, want)
+	}
+}
+
+func TestUnpackErrors(t *testing.T) {
+	req := httptest.NewRequest("GET", "/search?l=go&l=java&max=300&timeout=2m"+
+		"&level=medium&mail=gopher&limit=x&page=2", nil)
+	var got query
+	err := Unpack(req, &got)
+	errs, ok := err.(FieldErrors)
+	if !ok {
+		t.Fatalf("Unpack error = %v, want FieldErrors", err)
+	}
+	fields := make(map[string]bool)
+	for _, e := range errs {
+		fields[e.Field] = true
+	}
+	for _, name := range []string{"l", "max", "timeout", "level", "mail", "limit", "page", "addr.city"} {
+		if !fields[name] {
+			t.Errorf("no error reported for %s in\n%v", name, err)
+		}
+	}
+	if len(errs) != 8 {
+		t.Errorf("got %d errors, want 8:\n%v", len(errs), err)
+	}
+}
+
+func TestUnpackTimeBounds(t *testing.T) {
+	var got struct {
+		Since time.Time `http:"since" validate:"min=2020-01-01,max=2023-12-31T23:59"`
+		Bad   time.Time `http:"bad" validate:"min=yesterday"`
+	}
+	for _, test := range []struct {
+		query string
+		err   string
+	}{
+		{"since=2023-03-01", ""},
+		{"since=2020-01-01", ""},
+		{"since=2019-12-31", "since: must be at least 2020-01-01"},
+		{"since=2024-01-01", "since: must be at most 2023-12-31T23:59"},
+		{"since=2023-03-01&bad=2023-03-01", `bad: bad min=yesterday: invalid time "yesterday"`},
+	} {
+		err := Unpack(httptest.NewRequest("GET", "/?"+test.query, nil), &got)
+		if err == nil && test.err != "" || err != nil && err.Error() != test.err {
+			t.Errorf("Unpack(%s) = %v, want %q", test.query, err, test.err)
+		}
+	}
+}
+
+func TestUnpackPointerStruct(t *testing.T) {
+	type node struct {
+		Name string `http:"name"`
+		Next *node  `http:"next"`
+	}
+	var got struct {
+		Home *address `http:"home"`
+		Work *address `http:"work"`
+		Tree node     `http:"tree"`
+	}
+	got.Work = &address{City: "Havana", Zip: 1}
+	req := httptest.NewRequest("GET", "/?home.city=Matanzas&work.city=Havana&work.zip=2&tree.name=root", nil)
+	if err := Unpack(req, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Home == nil || *got.Home != (address{City: "Matanzas"}) {
+		t.Errorf("Home = %+v, want Matanzas", got.Home)
+	}
+	if *got.Work != (address{City: "Havana", Zip: 2}) {
+		t.Errorf("Work = %+v, want Havana 2", got.Work)
+	}
+	if got.Tree.Name != "root" || got.Tree.Next != nil {
+		t.Errorf("Tree = %+v", got.Tree)
+	}
+
+	// the fields of a nil pointer are only validated when it is given
+	got.Home = nil
+	err := Unpack(httptest.NewRequest("GET", "/?work.city=x&tree.next.next.name=y", nil), &got)
+	if err == nil || err.Error() != "tree.next.next.name: unknown parameter" {
+		t.Errorf("Unpack = %v", err)
+	}
+	if got.Home != nil {
+		t.Errorf("Home allocated with no parameters: %+v", got.Home)
+	}
+	err = Unpack(httptest.NewRequest("GET", "/?work.city=x&home.zip=3", nil), &got)
+	if err == nil || err.Error() != "home.city: required" {
+		t.Errorf("Unpack = %v", err)
+	}
+}
@@ -1,8 +1,7 @@
 package main
 
 import (
-	"strings"
-	"fmt"
+	"os"
 	"reflect"
 	"strconv"
 )
@@ -64,54 +63,7 @@ func formatAtom(v reflect.Value) string {
 
 // Display function
 func Display(name string, x interface{}) {
-	fmt.Printf("Display %s (%T):\n", name, x)
-	display(name, reflect.ValueOf(x))
-}
-
-func display(path string, v reflect.Value) {
-	switch v.Kind() {
-	case reflect.Invalid:
-		fmt.Printf("%s = invalid\n", path)
-	case reflect.Slice, reflect.Array:
-		for i := 0; i < v.Len(); i++ {
-			display(fmt.Sprintf("%s[%d]", path, i), v.Index(i))
-		}
-	case reflect.Struct:
-		for i := 0; i < v.NumField(); i++ {
-			fieldPath := fmt.Sprintf("%s.%s", path, v.Type().Field(i).Name)
-			field := v.Field(i).String()
-			search := v.Type().String()
-			if strings.Contains(field,search) {
-				fmt.Printf("%s = %v\n",path,formatAtom(v.Field(i)))
-				return
-			}
-			display(fieldPath, v.Field(i))
-		}
-	case reflect.Map:
-		for _, key := range v.MapKeys() {
-			// Ejercicio 12.1
-			if diff := reflect.ValueOf(key); diff.Kind() != reflect.String && diff.Kind() != reflect.Int &&
-				diff.Kind() != reflect.Float64 && diff.Kind() != reflect.Bool && diff.Kind() != reflect.Float32 {
-				display(fmt.Sprintf("%s", path), key)
-			}
-			display(fmt.Sprintf("%s[%s]", path, formatAtom(key)), v.MapIndex(key))
-		}
-	case reflect.Ptr:
-		if v.IsNil() {
-			fmt.Printf("%s = nil\n", path)
-		} else {
-			display(fmt.Sprintf("(%s)", path), v.Elem())
-		}
-	case reflect.Interface:
-		if v.IsNil() {
-			fmt.Printf("%s = nil\n", path)
-		} else {
-			fmt.Printf("%s.type = %s\n", path, v.Elem().Type())
-			display(path+".value", v.Elem())
-		}
-	default:
-		fmt.Printf("%s = %s\n", path, formatAtom(v))
-	}
+	(&Printer{W: os.Stdout}).Display(name, x)
 }
 
 // Movie struct
@@ -165,4 +117,7 @@ func main() {
 	c = cycle{20,&c}
 	Display("cycle struct",c)
 
+	p := &Printer{W: os.Stdout, Format: Tree, MaxDepth: 4}
+	p.Display("cycle struct",&c)
+
 }
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+/*
+	A Printer is a configurable Display. It writes to any io.Writer, stops at a
+	maximum depth and after a maximum number of elements per slice, array or map, and
+	prints map entries sorted by key, so the output is the same from run to run.
+
+	Pointers, maps and slices already being displayed on the current path are not
+	followed again, the value is printed as <cycle to path> instead. The same value
+	reached twice through different paths is printed twice.
+
+	Formats:
+
+	Lines      path = value, as Display
+	Tree       one indented line per node
+	JSONLines  one {"path", "type", "value"} object per value
+*/
+
+// Format of a Printer output
+type Format int
+
+// Formats
+const (
+	Lines Format = iota
+	Tree
+	JSONLines
+)
+
+// Printer struct
+type Printer struct {
+	W           io.Writer
+	MaxDepth    int // 0 means no limit
+	MaxElements int // 0 means no limit
+	Format      Format
+
+	onPath map[visit]string // references being displayed, and their paths
+	err    error
+}
+
+// visit is a reference that may lead back to itself
+type visit struct {
+	ptr uintptr
+	t   reflect.Type
+}
+
+// Display writes the value x named name, and returns the first write error
+func (p *Printer) Display(name string, x interface{}) error {
+	p.onPath = make(map[visit]string)
+	p.err = nil
+	switch p.Format {
+	case Lines:
+		p.printf("Display %s (%T):\n", name, x)
+	case Tree:
+		p.printf("%s (%T)\n", name, x)
+	}
+	p.display(name, "", 0, reflect.ValueOf(x))
+	return p.err
+}
+
+func (p *Printer) printf(format string, args ...interface{}) {
+	if p.err == nil {
+		_, p.err = fmt.Fprintf(p.W, format, args...)
+	}
+}
+
+// leaf prints a value that is not displayed further
+func (p *Printer) leaf(path, step string, depth int, v reflect.Value, text string) {
+	switch p.Format {
+	case Lines:
+		p.printf("%s = %s\n", path, text)
+	case Tree:
+		p.printf("%s%s = %s\n", strings.Repeat("  ", depth), step, text)
+	case JSONLines:
+		line := struct {
+			Path  string `json:"path"`
+			Type  string `json:"type,omitempty"`
+			Value string `json:"value"`
+		}{Path: path, Value: text}
+		if v.IsValid() {
+			line.Type = v.Type().String()
+		}
+		data, _ := json.Marshal(line)
+		p.printf("%s\n", data)
+	}
+}
+
+// node prints the heading of a composite value in the Tree format
+func (p *Printer) node(step string, depth int, v reflect.Value) {
+	if p.Format == Tree && depth > 0 {
+		p.printf("%s%s (%s)\n", strings.Repeat("  ", depth), step, v.Type())
+	}
+}
+
+func (p *Printer) display(path, step string, depth int, v reflect.Value) {
+	if p.err != nil {
+		return
+	}
+	switch v.Kind() {
+	case reflect.Invalid:
+		p.leaf(path, step, depth, v, "invalid")
+		return
+	case reflect.Slice, reflect.Array, reflect.Struct, reflect.Map, reflect.Ptr,
+		reflect.Interface:
+		if p.MaxDepth > 0 && depth >= p.MaxDepth {
+			p.leaf(path, step, depth, v, formatAtom(v)+" <max depth>")
+			return
+		}
+	default:
+		p.leaf(path, step, depth, v, formatAtom(v))
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice:
+		if v.IsNil() {
+			p.leaf(path, step, depth, v, "nil")
+			return
+		}
+		key := visit{v.Pointer(), v.Type()}
+		if to, ok := p.onPath[key]; ok {
+			p.leaf(path, step, depth, v, "<cycle to "+to+">")
+			return
+		}
+		p.onPath[key] = path
+		defer delete(p.onPath, key)
+	}
+
+	p.node(step, depth, v)
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if p.more(path, depth, i, v.Len()) {
+				break
+			}
+			step := fmt.Sprintf("[%d]", i)
+			p.display(path+step, step, depth+1, v.Index(i))
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			step := "." + v.Type().Field(i).Name
+			p.display(path+step, step, depth+1, v.Field(i))
+		}
+	case reflect.Map:
+		keys := v.MapKeys()
+		names := make(map[reflect.Value]string, len(keys))
+		for _, key := range keys {
+			names[key] = formatKey(key)
+		}
+		sort.SliceStable(keys, func(i, j int) bool {
+			return keyLess(keys[i], keys[j], names[keys[i]], names[keys[j]])
+		})
+		for i, key := range keys {
+			if p.more(path, depth, i, len(keys)) {
+				break
+			}
+			step := "[" + names[key] + "]"
+			p.display(path+step, step, depth+1, v.MapIndex(key))
+		}
+	case reflect.Ptr:
+		p.display(fmt.Sprintf("(%s)", path), "*", depth+1, v.Elem())
+	case reflect.Interface:
+		if v.IsNil() {
+			p.leaf(path, step, depth, v, "nil")
+			return
+		}
+		p.leaf(path+".type", ".type", depth+1, reflect.Value{}, v.Elem().Type().String())
+		p.display(path+".value", ".value", depth+1, v.Elem())
+	}
+}
+
+// more reports whether the i-th of n elements is past MaxElements, and prints how
+// many were left out
+func (p *Printer) more(path string, depth, i, n int) bool {
+	if p.MaxElements <= 0 || i < p.MaxElements {
+		return false
+	}
+	p.leaf(path+"[...]", "[...]", depth+1, reflect.Value{}, fmt.Sprintf("<%d more>", n-i))
+	return true
+}
+
+// keyLess orders map keys: numbers by value before other keys, which go by
+// their formatted names, and keys of different types by type name
+func keyLess(a, b reflect.Value, na, nb string) bool {
+	if a.Kind() == reflect.Interface && !a.IsNil() {
+		a = a.Elem()
+	}
+	if b.Kind() == reflect.Interface && !b.IsNil() {
+		b = b.Elem()
+	}
+	fa, aNum := number(a)
+	fb, bNum := number(b)
+	switch {
+	case aNum != bNum:
+		return aNum
+	case aNum && a.Kind() == b.Kind() && isInt(a.Kind()):
+		if a.Int() != b.Int() {
+			return a.Int() < b.Int()
+		}
+	case aNum && a.Kind() == b.Kind() && isUint(a.Kind()):
+		if a.Uint() != b.Uint() {
+			return a.Uint() < b.Uint()
+		}
+	case aNum:
+		if fa != fb {
+			return fa < fb
+		}
+	case na != nb:
+		return na < nb
+	}
+	return a.Type().String() < b.Type().String()
+}
+
+// number returns v as a float64, and whether it is a number
+func number(v reflect.Value) (float64, bool) {
+	switch {
+	case isInt(v.Kind()):
+		return float64(v.Int()), true
+	case isUint(v.Kind()):
+		return float64(v.Uint()), true
+	case v.Kind() == reflect.Float32 || v.Kind() == reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+func isInt(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Int64
+}
+
+func isUint(k reflect.Kind) bool {
+	return k >= reflect.Uint && k <= reflect.Uintptr
+}
+
+// formatKey prints a map key in full, Ejercicio 12.1
+func formatKey(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Struct:
+		fields := make([]string, v.NumField())
+		for i := range fields {
+			fields[i] = v.Type().Field(i).Name + ": " + formatKey(v.Field(i))
+		}
+		return v.Type().String() + "{" + strings.Join(fields, ", ") + "}"
+	case reflect.Array:
+		elems := make([]string, v.Len())
+		for i := range elems {
+			elems[i] = formatKey(v.Index(i))
+		}
+		return v.Type().String() + "{" + strings.Join(elems, ", ") + "}"
+	case reflect.Interface:
+		if v.IsNil() {
+			return "nil"
+		}
+		return formatKey(v.Elem())
+	}
+	return formatAtom(v)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPrinter(t *testing.T) {
+	type node struct {
+		Name  string
+		Next  *node
+		Kids  []interface{}
+		Attrs map[string]int
+	}
+	a := &node{Name: "a", Attrs: map[string]int{"z": 1, "b": 2, "m": 3}}
+	a.Next = &node{Name: "b", Next: a}
+	a.Kids = []interface{}{1, 2, 3, a.Kids}
+	a.Kids[3] = a.Kids
+
+	var buf bytes.Buffer
+	p := &Printer{W: &buf}
+	if err := p.Display("a", a); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"((a).Next).Next = <cycle to a>\n",
+		"(a).Kids[3].value = <cycle to (a).Kids>\n",
+		"(a).Attrs[\"b\"] = 2\n(a).Attrs[\"m\"] = 3\n(a).Attrs[\"z\"] = 1\n",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Display output lacks %q:\n%s", want, buf.String())
+		}
+	}
+
+	buf.Reset()
+	p = &Printer{W: &buf, MaxDepth: 3, MaxElements: 2, Format: JSONLines}
+	p.Display("a", a)
+	for _, want := range []string{
+		`{"path":"(a).Kids[...]","value":"\u003c2 more\u003e"}`,
+		`{"path":"((a).Next)","type":"main.node","value":"main.node value \u003cmax depth\u003e"}`,
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("JSON lines output lacks %s:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestPrinterMapKeys(t *testing.T) {
+	for _, test := range []struct {
+		m    interface{}
+		want string
+	}{
+		{map[int]bool{10: true, 2: true, 1: true, -3: true},
+			"m[-3] = true\nm[1] = true\nm[2] = true\nm[10] = true\n"},
+		{map[uint8]bool{10: true, 2: true, 1: true},
+			"m[1] = true\nm[2] = true\nm[10] = true\n"},
+		{map[interface{}]bool{"b": true, 10: true, uint(5): true, "a": true, 1: true},
+			"m[1] = true\nm[5] = true\nm[10] = true\nm[\"a\"] = true\nm[\"b\"] = true\n"},
+	} {
+		var buf bytes.Buffer
+		if err := (&Printer{W: &buf}).Display("m", test.m); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != "Display m ("+reflect.TypeOf(test.m).String()+"):\n"+test.want {
+			t.Errorf("Display(%v) =\n%s", test.m, buf.String())
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package main 
 
 import (
+	"flag"
+	"os"
 	"time"
 	"strings"
 	"fmt"
@@ -25,7 +27,31 @@ func Print(x interface{}) {
 	}
 }
 
-func main() {
-	Print(time.Hour)
+var (
+	ifaces = flag.String("iface", "io,fmt,sort,encoding", "import paths of the interfaces to match, comma separated")
+	near   = flag.Int("near", 1, "report interfaces missed by at most this many methods")
+	gen    = flag.String("gen", "", "print an interface of this name with the methods of the type")
+)
 
-}
\ No newline at end of file
+func main() {
+	flag.Parse()
+	if flag.NArg() == 0 {
+		Print(time.Hour)
+		return
+	}
+	for _, arg := range flag.Args() {
+		t, err := LookupType(arg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "methods: %v\n", err)
+			os.Exit(1)
+		}
+		if *gen != "" {
+			fmt.Print(Declaration(*gen, t))
+			continue
+		}
+		if err := Explain(t, strings.Split(*ifaces, ","), *near); err != nil {
+			fmt.Fprintf(os.Stderr, "methods: %v\n", err)
+			os.Exit(1)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/importer"
+	"go/token"
+	"go/types"
+	"sort"
+	"strings"
+)
+
+/*
+	Print lists the methods of the dynamic type of a value, which is only known
+	through reflection. The method sets of a named type T and of *T, and the
+	interfaces they satisfy, are found from the type checker instead:
+
+	methods time.Duration
+	methods -iface io,fmt,sort,encoding -near 2 bytes.Buffer
+	methods -gen Buffer bytes.Buffer
+
+	A type narrowly misses an interface when it has some of its methods, and at most
+	near of them are absent or have another signature. Methods declared on *T only
+	make T satisfy an interface through a pointer, which is reported as well.
+*/
+
+var imports = importer.ForCompiler(token.NewFileSet(), "source", nil)
+
+// qualifier writes package names, as in source code
+func qualifier(p *types.Package) string { return p.Name() }
+
+// LookupType finds the named type of a name like time.Duration or
+// encoding/json.Decoder
+func LookupType(name string) (*types.Named, error) {
+	dot := strings.LastIndex(name, ".")
+	if dot < 0 {
+		return nil, fmt.Errorf("%s is not of the form package.Type", name)
+	}
+	pkg, err := imports.Import(name[:dot])
+	if err != nil {
+		return nil, err
+	}
+	tn, ok := pkg.Scope().Lookup(name[dot+1:]).(*types.TypeName)
+	if !ok {
+		return nil, fmt.Errorf("no type %s in %s", name[dot+1:], pkg.Path())
+	}
+	named, ok := tn.Type().(*types.Named)
+	if !ok {
+		return nil, fmt.Errorf("%s is an alias", name)
+	}
+	return named, nil
+}
+
+// MethodSets returns the methods of T, and those that only *T has
+func MethodSets(t types.Type) (value, pointer []*types.Func) {
+	ms := types.NewMethodSet(t)
+	for i := 0; i < ms.Len(); i++ {
+		value = append(value, ms.At(i).Obj().(*types.Func))
+	}
+	pms := types.NewMethodSet(types.NewPointer(t))
+	for i := 0; i < pms.Len(); i++ {
+		if ms.Lookup(pms.At(i).Obj().Pkg(), pms.At(i).Obj().Name()) == nil {
+			pointer = append(pointer, pms.At(i).Obj().(*types.Func))
+		}
+	}
+	return value, pointer
+}
+
+// Match of a type against an interface
+type Match struct {
+	Interface   string   // package.Name
+	PointerOnly bool     // satisfied by *T but not T
+	Missing     []string // methods absent or with another signature
+}
+
+// Interfaces compares t with every exported interface of the packages at the
+// given import paths, and returns those satisfied and those missed by at most near
+// methods. Empty interfaces are left out.
+func Interfaces(t types.Type, paths []string, near int) (satisfied, missed []Match, err error) {
+	for _, path := range paths {
+		pkg, err := imports.Import(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, name := range pkg.Scope().Names() {
+			tn, ok := pkg.Scope().Lookup(name).(*types.TypeName)
+			if !ok || !tn.Exported() {
+				continue
+			}
+			iface, ok := tn.Type().Underlying().(*types.Interface)
+			if !ok || iface.NumMethods() == 0 || !iface.IsMethodSet() {
+				continue
+			}
+			m := Match{Interface: pkg.Name() + "." + name}
+			switch {
+			case types.Implements(t, iface):
+				satisfied = append(satisfied, m)
+			case types.Implements(types.NewPointer(t), iface):
+				m.PointerOnly = true
+				satisfied = append(satisfied, m)
+			default:
+				var absent int
+				m.Missing, absent = missing(types.NewPointer(t), iface)
+				if len(m.Missing) <= near && absent < iface.NumMethods() {
+					missed = append(missed, m)
+				}
+			}
+		}
+	}
+	sort.SliceStable(missed, func(i, j int) bool { return len(missed[i].Missing) < len(missed[j].Missing) })
+	return satisfied, missed, nil
+}
+
+// missing describes the methods of iface that t lacks, and counts those of them
+// it has no method of the same name for
+func missing(t types.Type, iface *types.Interface) (list []string, absent int) {
+	ms := types.NewMethodSet(t)
+	for i := 0; i < iface.NumMethods(); i++ {
+		want := iface.Method(i)
+		sel := ms.Lookup(want.Pkg(), want.Name())
+		switch {
+		case sel == nil:
+			list = append(list, signature(want))
+			absent++
+		case !types.Identical(sel.Type(), want.Type()):
+			list = append(list, signature(want)+", has "+signature(sel.Obj().(*types.Func)))
+		}
+	}
+	return list, absent
+}
+
+// signature returns the declaration of a method in an interface, as
+// Read(p []byte) (n int, err error)
+func signature(f *types.Func) string {
+	var b bytes.Buffer
+	b.WriteString(f.Name())
+	types.WriteSignature(&b, f.Type().(*types.Signature), qualifier)
+	return b.String()
+}
+
+// Declaration returns an interface type named name holding the exported methods of
+// *t, which include those of t
+func Declaration(name string, t types.Type) string {
+	value, pointer := MethodSets(t)
+	methods := append(value, pointer...)
+	sort.Slice(methods, func(i, j int) bool { return methods[i].Name() < methods[j].Name() })
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "type %s interface {\n", name)
+	for _, m := range methods {
+		if m.Exported() {
+			fmt.Fprintf(&b, "\t%s\n", signature(m))
+		}
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// Explain prints the method sets of t, and the interfaces of the packages at paths
+// that it satisfies or narrowly misses
+func Explain(t *types.Named, paths []string, near int) error {
+	name := types.TypeString(t, qualifier)
+	value, pointer := MethodSets(t)
+	fmt.Printf("type %s\n\nmethods of %s:\n", name, name)
+	for _, m := range value {
+		fmt.Printf("\tfunc (%s) %s\n", name, signature(m))
+	}
+	fmt.Printf("\nmethods of *%s only:\n", name)
+	for _, m := range pointer {
+		fmt.Printf("\tfunc (*%s) %s\n", name, signature(m))
+	}
+
+	satisfied, missed, err := Interfaces(t, paths, near)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("\nsatisfies:\n")
+	for _, m := range satisfied {
+		if m.PointerOnly {
+			fmt.Printf("\t%s through *%s\n", m.Interface, name)
+		} else {
+			fmt.Printf("\t%s\n", m.Interface)
+		}
+	}
+	fmt.Printf("\nnarrowly misses:\n")
+	for _, m := range missed {
+		fmt.Printf("\t%s, lacking %s\n", m.Interface, strings.Join(m.Missing, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMethodSets(t *testing.T) {
+	buf, err := LookupType("bytes.Buffer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, pointer := MethodSets(buf)
+	if len(value) != 0 || len(pointer) == 0 {
+		t.Errorf("bytes.Buffer has %d value and %d pointer methods", len(value), len(pointer))
+	}
+	d, err := LookupType("time.Duration")
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, pointer = MethodSets(d)
+	if len(value) == 0 || len(pointer) != 0 {
+		t.Errorf("time.Duration has %d value and %d pointer methods", len(value), len(pointer))
+	}
+	if _, err := LookupType("time.NoSuchType"); err == nil {
+		t.Errorf("LookupType(time.NoSuchType) did not fail")
+	}
+}
+
+func TestInterfaces(t *testing.T) {
+	buf, _ := LookupType("bytes.Buffer")
+	satisfied, missed, err := Interfaces(buf, []string{"io", "sort"}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := make(map[string]Match)
+	for _, m := range append(satisfied, missed...) {
+		found[m.Interface] = m
+	}
+	if m, ok := found["io.ReadWriter"]; !ok || !m.PointerOnly || m.Missing != nil {
+		t.Errorf("io.ReadWriter: %+v", m)
+	}
+	if m := found["io.ReadCloser"]; len(m.Missing) != 1 || m.Missing[0] != "Close() error" {
+		t.Errorf("io.ReadCloser: %+v", m)
+	}
+	if m := found["sort.Interface"]; len(m.Missing) != 2 {
+		t.Errorf("sort.Interface: %+v", m)
+	}
+	if _, ok := found["io.Closer"]; ok {
+		t.Errorf("io.Closer reported, though Buffer has none of its methods")
+	}
+
+	// a method of the right name but another signature is a near miss
+	tm, _ := LookupType("time.Time")
+	_, missed, _ = Interfaces(tm, []string{"fmt"}, 1)
+	want := "Format(f fmt.State, verb rune), has Format(layout string) string"
+	if len(missed) != 1 || missed[0].Interface != "fmt.Formatter" || missed[0].Missing[0] != want {
+		t.Errorf("time.Time misses %+v", missed)
+	}
+}
+
+func TestDeclaration(t *testing.T) {
+	d, _ := LookupType("time.Duration")
+	decl := Declaration("Durationer", d)
+	if !strings.HasPrefix(decl, "type Durationer interface {\n\tAbs() time.Duration\n") ||
+		!strings.Contains(decl, "\tRound(m time.Duration) time.Duration\n") ||
+		strings.Contains(decl, "format") {
+		t.Errorf("Declaration =\n%s", decl)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"testing"
+
+	"decoding/sexpr"
+)
+
+const strangelove = `
+	((Title "Dr.Strangelove")
+	 (Subtitle "How I learned to Stop Worrying and Love the Bomb")
+	 (Year 1964)
+	 (Actor (("Dr. Streangelove" "Peter Sellers")
+	         ("Maj. T.J \"King\" Kong" "Slim Pickens")))
+	 (Oscars ("Best Actor (Nomin.)" "Best Picture (Nomin. )"))
+	 (Sequel "ptr" "test test")
+	 (TestInter "[]int" "1,2,3"))`
+
+func TestUnmarshal(t *testing.T) {
+	var movie Movie
+	if err := Unmarshal([]byte(strangelove), &movie); err != nil {
+		t.Fatal(err)
+	}
+	if movie.Year != 1964 || movie.Actor[`Maj. T.J "King" Kong`] != "Slim Pickens" ||
+		len(movie.Oscars) != 2 || movie.Sequel == nil || *movie.Sequel != "test test" {
+		t.Errorf("Unmarshal(strangelove) = %+v", movie)
+	}
+}
+
+func FuzzUnmarshal(f *testing.F) {
+	for _, seed := range []string{
+		strangelove,
+		"()",
+		"((Year 1964) (Color true))",
+		`((Oscars ("a" "b")) (Actor (("x" "y"))))`,
+		"((Title nil) (Year 1.5e3))",
+	} {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var movie Movie
+		Unmarshal(data, &movie) // must not panic
+
+		var tree sexpr.Value
+		if err := Unmarshal(data, &tree); err != nil {
+			return
+		}
+		// a parsed tree must survive re-encoding unchanged
+		var again sexpr.Value
+		if err := Unmarshal([]byte(tree.String()), &again); err != nil {
+			t.Fatalf("Unmarshal(%s): %v", tree.String(), err)
+		}
+		if tree.String() != again.String() {
+			t.Fatalf("re-encoding changed the tree:\n%s\n%s", tree.String(), again.String())
+		}
+	})
+}
@@ -0,0 +1,239 @@
+package sexpr
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"text/scanner"
+)
+
+/*
+	A Decoder reads successive values from one stream, each into a Go variable or
+	a *Value. It reads exactly one value per call and leaves the rest of the stream
+	for the next one; io.EOF reports the end of the stream.
+*/
+
+// Decoder struct
+type Decoder struct {
+	lex     *lexer
+	started bool
+}
+
+// NewDecoder returns a decoder reading from r
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{lex: newLexer(r)}
+}
+
+// Decode reads the next value into the variable pointed to by out. A *Value
+// receives the generic tree of data.
+func (d *Decoder) Decode(out interface{}) (err error) {
+	defer func() {
+		if x := recover(); x != nil {
+			err = fmt.Errorf("error at %s: %v", d.lex.scan.Position, x)
+		}
+	}()
+	if !d.started {
+		d.started = true
+		d.lex.next()
+	}
+	if d.lex.token == scanner.EOF {
+		return io.EOF
+	}
+	if tree, ok := out.(*Value); ok {
+		*tree = *read(d.lex)
+		return nil
+	}
+	decode(d.lex, reflect.ValueOf(out).Elem())
+	return nil
+}
+
+// Unmarshal decodes data into the variable pointed to by out. A *Value receives
+// the generic tree of data. Data must hold exactly one value.
+func Unmarshal(data []byte, out interface{}) error {
+	if tree, ok := out.(*Value); ok {
+		return tree.UnmarshalText(data)
+	}
+	dec := NewDecoder(bytes.NewReader(data))
+	if err := dec.Decode(out); err == io.EOF {
+		return io.ErrUnexpectedEOF
+	} else if err != nil {
+		return err
+	}
+	if dec.lex.token != scanner.EOF {
+		return fmt.Errorf("error at %s: unexpected token %q after value",
+			dec.lex.scan.Position, dec.lex.text())
+	}
+	return nil
+}
+
+func (lex *lexer) consume(want rune) {
+	if lex.token != want {
+		panic(fmt.Sprintf("got %q, want %q", lex.text(), want))
+	}
+	lex.next()
+}
+
+func decode(lex *lexer, v reflect.Value) {
+	if v.Kind() == reflect.Ptr && lex.text() != "nil" && lex.text() != `"ptr"` {
+		// a pointer is written as the value it points to
+		elem := reflect.New(v.Type().Elem())
+		decode(lex, elem.Elem())
+		v.Set(elem)
+		return
+	}
+	switch lex.token {
+	case scanner.Ident:
+		switch lex.text() {
+		case "nil":
+			v.Set(reflect.Zero(v.Type()))
+			lex.next()
+			return
+		case "true":
+			v.SetBool(true)
+			lex.next()
+			return
+		case "false":
+			v.SetBool(false)
+			lex.next()
+			return
+		}
+	case scanner.String:
+		s, err := strconv.Unquote(lex.text())
+		if err != nil {
+			panic(fmt.Sprintf("cannot decode interface name %v", v.Type()))
+		}
+		switch s {
+		case "ptr":
+			var stringdata *string
+			lex.next()
+			value, er := strconv.Unquote(lex.text())
+			if er != nil {
+				panic(fmt.Sprintf("can not decode ptr name %v", v.Type()))
+			}
+			stringdata = &value
+			elem := reflect.ValueOf(stringdata)
+			v.Set(elem)
+			lex.next()
+			return
+
+		case "[]int":
+			var interfacedata string
+			lex.next()
+			value, err := strconv.Unquote(lex.text())
+			if err != nil {
+				panic(fmt.Sprintf("cannot decode interface name %v", v.Type()))
+			}
+			interfacedata = value
+			elem := reflect.ValueOf(interfacedata)
+			v.Set(elem)
+			lex.next()
+			return
+
+		default:
+			v.SetString(s)
+			lex.next()
+			return
+
+		}
+
+	case scanner.Int, scanner.Float: // Ejercicio 12.10
+		setNumber(v, lex.text())
+		lex.next()
+		return
+	case '-':
+		lex.next()
+		if lex.token != scanner.Int && lex.token != scanner.Float {
+			panic(fmt.Sprintf("unexpected token %q after '-'", lex.text()))
+		}
+		setNumber(v, "-"+lex.text())
+		lex.next()
+		return
+
+	case '(':
+		lex.next()
+		decodeList(lex, v)
+		lex.next()
+		return
+	}
+	panic(fmt.Sprintf("unexpected token %q", lex.text()))
+
+}
+
+// setNumber sets v, of any numeric kind, to the number written as text
+func setNumber(v reflect.Value, text string) {
+	var err error
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var i int64
+		if i, err = strconv.ParseInt(text, 10, v.Type().Bits()); err == nil {
+			v.SetInt(i)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		var u uint64
+		if u, err = strconv.ParseUint(text, 10, v.Type().Bits()); err == nil {
+			v.SetUint(u)
+		}
+	case reflect.Float32, reflect.Float64:
+		var f float64
+		if f, err = strconv.ParseFloat(text, v.Type().Bits()); err == nil {
+			v.SetFloat(f)
+		}
+	default:
+		panic(fmt.Sprintf("cannot decode number %s into %v", text, v.Type()))
+	}
+	if err != nil {
+		panic(err)
+	}
+}
+
+func decodeList(lex *lexer, v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Array: // (item ...)
+		for i := 0; !endList(lex); i++ {
+			decode(lex, v.Index(i))
+		}
+	case reflect.Slice: // (item ...)
+		for !endList(lex) {
+			item := reflect.New(v.Type().Elem()).Elem()
+			decode(lex, item)
+			v.Set(reflect.Append(v, item))
+		}
+	case reflect.Struct: // ((name value) ...)
+		for !endList(lex) {
+			lex.consume('(')
+			if lex.token != scanner.Ident {
+				panic(fmt.Sprintf("got token %q, want field name", lex.text()))
+			}
+			name := lex.text()
+			lex.next()
+			decode(lex, v.FieldByName(name))
+			lex.consume(')')
+		}
+	case reflect.Map: // ((key value) ...)
+		v.Set(reflect.MakeMap(v.Type()))
+		for !endList(lex) {
+			lex.consume('(')
+			key := reflect.New(v.Type().Key()).Elem()
+			decode(lex, key)
+			value := reflect.New(v.Type().Elem()).Elem()
+			decode(lex, value)
+			v.SetMapIndex(key, value)
+			lex.consume(')')
+		}
+
+	default:
+		panic(fmt.Sprintf("cannot decode list into %v", v.Type()))
+	}
+}
+
+func endList(lex *lexer) bool {
+	switch lex.token {
+	case scanner.EOF:
+		panic("end of file")
+	case ')':
+		return true
+	}
+	return false
+}
@@ -0,0 +1,97 @@
+package sexpr
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestDecoderStream(t *testing.T) {
+	type movie struct {
+		Title string
+		Year  int
+	}
+	dec := NewDecoder(strings.NewReader(`((Title "a") (Year 1)) (x "y") ((Title "b"))` + "\n(1 2)"))
+	var m movie
+	if err := dec.Decode(&m); err != nil || m.Title != "a" || m.Year != 1 {
+		t.Fatalf("first Decode = %+v, %v", m, err)
+	}
+	var tree Value
+	if err := dec.Decode(&tree); err != nil || tree.String() != `(x "y")` {
+		t.Fatalf("second Decode = %s, %v", &tree, err)
+	}
+	m = movie{}
+	if err := dec.Decode(&m); err != nil || m.Title != "b" {
+		t.Fatalf("third Decode = %+v, %v", m, err)
+	}
+	if err := dec.Decode(&tree); err != nil || tree.String() != "(1 2)" {
+		t.Fatalf("fourth Decode = %s, %v", &tree, err)
+	}
+	if err := dec.Decode(&tree); err != io.EOF {
+		t.Errorf("Decode at the end = %v, want io.EOF", err)
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	var m struct {
+		Title  string
+		Year   int
+		Oscars []string
+		Actor  map[string]string
+	}
+	if err := Unmarshal([]byte(movie), &m); err != nil {
+		t.Fatal(err)
+	}
+	if m.Title != "Dr. Strangelove" || len(m.Oscars) != 3 || m.Actor["Gen. Buck Turgidson"] != "George C. Scott" {
+		t.Errorf("Unmarshal(movie) = %+v", m)
+	}
+	for _, input := range []string{"", "((Title", `((Title 1))`} {
+		if err := Unmarshal([]byte(input), &m); err == nil {
+			t.Errorf("Unmarshal(%q) succeeded, want error", input)
+		}
+	}
+	// trailing data is an error whatever the value is decoded into
+	for _, input := range []string{`((Title "a")) ((Title "b"))`, `((Year 1)) x`} {
+		var tree Value
+		for _, out := range []interface{}{&m, &tree} {
+			if err := Unmarshal([]byte(input), out); err == nil || !strings.Contains(err.Error(), "after value") {
+				t.Errorf("Unmarshal(%q) into %T = %v, want an error after the value", input, out, err)
+			}
+		}
+	}
+}
+
+func TestUnmarshalNumbersAndPointers(t *testing.T) {
+	type numbers struct {
+		I     int8
+		U     uint16
+		F     float32
+		G     float64
+		P     *int
+		Q     *[]string
+		N     *int
+		Neg   []int
+		Split map[int]*float64
+	}
+	var x numbers
+	input := `((I -8) (U 65535) (F 1.5) (G -2) (P 7) (Q ("a")) (N nil) (Neg (-1 -2.5e3))
+		(Split ((-1 0.25) (2 nil))))`
+	if err := Unmarshal([]byte(input), &x); err == nil {
+		t.Errorf("Unmarshal of a float into an int succeeded")
+	}
+	input = strings.Replace(input, "-2.5e3", "-2500", 1)
+	x = numbers{}
+	if err := Unmarshal([]byte(input), &x); err != nil {
+		t.Fatal(err)
+	}
+	if x.I != -8 || x.U != 65535 || x.F != 1.5 || x.G != -2 || x.P == nil || *x.P != 7 ||
+		x.Q == nil || len(*x.Q) != 1 || x.N != nil || len(x.Neg) != 2 || x.Neg[1] != -2500 ||
+		*x.Split[-1] != 0.25 || x.Split[2] != nil {
+		t.Errorf("Unmarshal(%s) = %+v", input, x)
+	}
+	for _, input := range []string{"((I 128))", "((U -1))", "((I - x))", `((P "x"))`} {
+		if err := Unmarshal([]byte(input), &x); err == nil {
+			t.Errorf("Unmarshal(%s) succeeded, want error", input)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package sexpr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"text/scanner"
+)
+
+/*
+	A path selects a value inside a tree, one step at a time:
+
+	Oscars[2]                 third item of the Oscars field
+	(Actor "Dr. Strangelove") entry of the Actor map
+	Actor."Dr. Strangelove"   the same, written with a dot
+	(Ranks 2)                 entry 2 of a map with integer keys
+	(Oscars 2)                or, with no such entry, the third item
+
+	A name, a quoted string or an integer looks up the pair (key value) whose head
+	has that text, as struct fields and map entries are encoded. An integer with no
+	such pair selects the n-th item of a list, and [n] always does. Parentheses and
+	dots are only separators.
+*/
+
+type step struct {
+	key     *Value // Symbol, String or Int, nil for an index
+	index   int
+	isIndex bool
+}
+
+func (s step) String() string {
+	if s.isIndex {
+		return fmt.Sprintf("[%d]", s.index)
+	}
+	if s.key.kind == String {
+		return "." + strconv.Quote(s.key.text)
+	}
+	return "." + s.key.text
+}
+
+func parsePath(path string) (steps []step, err error) {
+	lex := newLexer(strings.NewReader(path))
+	defer func() {
+		if x := recover(); x != nil {
+			err = fmt.Errorf("bad path %q: %v", path, x)
+		}
+	}()
+	for lex.next(); lex.token != scanner.EOF; lex.next() {
+		switch lex.token {
+		case '(', ')', '.':
+			// separators
+		case scanner.Ident:
+			steps = append(steps, step{key: NewSymbol(lex.text())})
+		case scanner.String, scanner.RawString:
+			s, err := strconv.Unquote(lex.text())
+			if err != nil {
+				panic(fmt.Sprintf("bad string %s", lex.text()))
+			}
+			steps = append(steps, step{key: NewString(s)})
+		case scanner.Int:
+			steps = append(steps, step{key: &Value{kind: Int, text: lex.text()}, index: atoi(lex.text())})
+		case '[':
+			lex.next()
+			if lex.token != scanner.Int {
+				panic(fmt.Sprintf("got %q, want index", lex.text()))
+			}
+			steps = append(steps, step{index: atoi(lex.text()), isIndex: true})
+			lex.next()
+			if lex.token != ']' {
+				panic(fmt.Sprintf("got %q, want ']'", lex.text()))
+			}
+		default:
+			panic(fmt.Sprintf("unexpected token %q", lex.text()))
+		}
+	}
+	return steps, nil
+}
+
+func atoi(s string) int {
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		panic(err)
+	}
+	return i
+}
+
+// on returns the step s takes in the list v: an integer key with no pair in v
+// is an index
+func (s step) on(v *Value) step {
+	if !s.isIndex && s.key.kind == Int && v.pair(s.key.text) == nil {
+		return step{index: s.index, isIndex: true}
+	}
+	return s
+}
+
+// walk follows one step from v, returning nil if there is nothing there
+func (s step) walk(v *Value) (*Value, error) {
+	if v.kind != List {
+		return nil, fmt.Errorf("cannot select %s in %s", s, v.kind)
+	}
+	if s = s.on(v); s.isIndex {
+		return v.Index(s.index), nil
+	}
+	return v.Lookup(s.key.text), nil
+}
+
+// Query returns the value selected by path
+func (v *Value) Query(path string) (*Value, error) {
+	steps, err := parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	x := v
+	for i, s := range steps {
+		if x, err = s.walk(x); err != nil {
+			return nil, fmt.Errorf("%s: %v", formatSteps(steps[:i]), err)
+		}
+		if x == nil {
+			return nil, fmt.Errorf("%s: not found", formatSteps(steps[:i+1]))
+		}
+	}
+	return x, nil
+}
+
+// Set replaces the value selected by path with x. A missing last key adds the
+// pair (key x), an index one past the end appends x.
+func (v *Value) Set(path string, x *Value) error {
+	parent, last, err := v.parent(path)
+	if err != nil {
+		return err
+	}
+	if last = last.on(parent); last.isIndex {
+		switch {
+		case last.index >= 0 && last.index < len(parent.items):
+			parent.items[last.index] = x
+		case last.index == len(parent.items):
+			parent.items = append(parent.items, x)
+		default:
+			return fmt.Errorf("%s: index out of range", path)
+		}
+		return nil
+	}
+	if pair := parent.pair(last.key.text); pair != nil {
+		pair.items[1] = x
+		return nil
+	}
+	parent.items = append(parent.items, NewList(last.key, x))
+	return nil
+}
+
+// Delete removes the value selected by path, or the whole pair for a key
+func (v *Value) Delete(path string) error {
+	parent, last, err := v.parent(path)
+	if err != nil {
+		return err
+	}
+	last = last.on(parent)
+	i := last.index
+	if !last.isIndex {
+		i = -1
+		pair := parent.pair(last.key.text)
+		for j, item := range parent.items {
+			if item == pair {
+				i = j
+				break
+			}
+		}
+	}
+	if i < 0 || i >= len(parent.items) {
+		return fmt.Errorf("%s: not found", path)
+	}
+	parent.items = append(parent.items[:i], parent.items[i+1:]...)
+	return nil
+}
+
+// parent returns the list holding the value selected by path and the last step
+func (v *Value) parent(path string) (*Value, step, error) {
+	steps, err := parsePath(path)
+	if err != nil {
+		return nil, step{}, err
+	}
+	if len(steps) == 0 {
+		return nil, step{}, fmt.Errorf("empty path")
+	}
+	parent := v
+	for i, s := range steps[:len(steps)-1] {
+		if parent, err = s.walk(parent); err != nil {
+			return nil, step{}, fmt.Errorf("%s: %v", formatSteps(steps[:i]), err)
+		}
+		if parent == nil {
+			return nil, step{}, fmt.Errorf("%s: not found", formatSteps(steps[:i+1]))
+		}
+	}
+	if parent.kind != List {
+		return nil, step{}, fmt.Errorf("%s: %s is not a list",
+			formatSteps(steps[:len(steps)-1]), parent.kind)
+	}
+	return parent, steps[len(steps)-1], nil
+}
+
+func formatSteps(steps []step) string {
+	var b strings.Builder
+	for _, s := range steps {
+		b.WriteString(s.String())
+	}
+	if b.Len() == 0 {
+		return "."
+	}
+	return b.String()
+}
@@ -0,0 +1,274 @@
+// Package sexpr decodes S-expressions into Go variables, or into a dynamic tree
+// of values for data that has no Go type to be decoded into.
+package sexpr
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"text/scanner"
+)
+
+// Kind of a Value
+type Kind int
+
+// Kinds of Value
+const (
+	Symbol Kind = iota // nil, true, Title
+	String             // "Dr. Strangelove"
+	Int                // 1964
+	Float              // 3.5
+	List               // (a b c)
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Symbol:
+		return "symbol"
+	case String:
+		return "string"
+	case Int:
+		return "int"
+	case Float:
+		return "float"
+	case List:
+		return "list"
+	}
+	return "kind(" + strconv.Itoa(int(k)) + ")"
+}
+
+// Value is either an atom or a list of values
+type Value struct {
+	kind  Kind
+	text  string // atom text, unquoted for strings
+	items []*Value
+}
+
+// NewSymbol returns a symbol atom
+func NewSymbol(name string) *Value { return &Value{kind: Symbol, text: name} }
+
+// NewString returns a string atom
+func NewString(s string) *Value { return &Value{kind: String, text: s} }
+
+// NewInt returns an integer atom
+func NewInt(i int64) *Value { return &Value{kind: Int, text: strconv.FormatInt(i, 10)} }
+
+// NewFloat returns a floating-point atom
+func NewFloat(f float64) *Value {
+	return &Value{kind: Float, text: strconv.FormatFloat(f, 'g', -1, 64)}
+}
+
+// NewList returns a list holding items
+func NewList(items ...*Value) *Value { return &Value{kind: List, items: items} }
+
+// Kind method
+func (v *Value) Kind() Kind { return v.kind }
+
+// IsAtom reports whether v is not a list
+func (v *Value) IsAtom() bool { return v.kind != List }
+
+// Text returns the text of an atom, strings are unquoted
+func (v *Value) Text() string { return v.text }
+
+// Len returns the number of items of a list
+func (v *Value) Len() int { return len(v.items) }
+
+// Index returns the i-th item of a list, or nil if out of range
+func (v *Value) Index(i int) *Value {
+	if i < 0 || i >= len(v.items) {
+		return nil
+	}
+	return v.items[i]
+}
+
+// Items returns the items of a list
+func (v *Value) Items() []*Value { return v.items }
+
+// Int returns the value of an integer atom
+func (v *Value) Int() (int64, error) {
+	if v.kind != Int {
+		return 0, fmt.Errorf("%s is not an int", v.kind)
+	}
+	return strconv.ParseInt(v.text, 0, 64)
+}
+
+// Float returns the value of a numeric atom
+func (v *Value) Float() (float64, error) {
+	if v.kind != Int && v.kind != Float {
+		return 0, fmt.Errorf("%s is not a number", v.kind)
+	}
+	return strconv.ParseFloat(v.text, 64)
+}
+
+// Bool returns the value of the symbols true and false
+func (v *Value) Bool() (bool, error) {
+	if v.kind == Symbol && (v.text == "true" || v.text == "false") {
+		return v.text == "true", nil
+	}
+	return false, fmt.Errorf("%s %s is not a bool", v.kind, v)
+}
+
+// IsNil reports whether v is the symbol nil
+func (v *Value) IsNil() bool { return v.kind == Symbol && v.text == "nil" }
+
+// Lookup returns the value of the pair (key value) whose head has the text key,
+// as found in the lists of struct fields and map entries, or nil.
+func (v *Value) Lookup(key string) *Value {
+	if pair := v.pair(key); pair != nil {
+		return pair.items[1]
+	}
+	return nil
+}
+
+func (v *Value) pair(key string) *Value {
+	for _, item := range v.items {
+		if item.kind == List && len(item.items) >= 2 &&
+			item.items[0].IsAtom() && item.items[0].text == key {
+			return item
+		}
+	}
+	return nil
+}
+
+// Append adds items to the end of a list
+func (v *Value) Append(items ...*Value) error {
+	if v.kind != List {
+		return fmt.Errorf("cannot append to %s", v.kind)
+	}
+	v.items = append(v.items, items...)
+	return nil
+}
+
+// String returns the encoding of v
+func (v *Value) String() string {
+	var buf bytes.Buffer
+	v.Encode(&buf)
+	return buf.String()
+}
+
+// MarshalText method
+func (v *Value) MarshalText() ([]byte, error) { return []byte(v.String()), nil }
+
+// UnmarshalText method
+func (v *Value) UnmarshalText(data []byte) error {
+	x, err := Parse(data)
+	if err != nil {
+		return err
+	}
+	*v = *x
+	return nil
+}
+
+// Encode writes v to w
+func (v *Value) Encode(w io.Writer) error {
+	var buf bytes.Buffer
+	encode(&buf, v)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func encode(buf *bytes.Buffer, v *Value) {
+	switch v.kind {
+	case String:
+		buf.WriteString(strconv.Quote(v.text))
+	case List:
+		buf.WriteByte('(')
+		for i, item := range v.items {
+			if i > 0 {
+				buf.WriteByte(' ')
+			}
+			encode(buf, item)
+		}
+		buf.WriteByte(')')
+	default:
+		buf.WriteString(v.text)
+	}
+}
+
+// parser
+
+type lexer struct {
+	scan  scanner.Scanner
+	token rune
+}
+
+func (lex *lexer) next()        { lex.token = lex.scan.Scan() }
+func (lex *lexer) text() string { return lex.scan.TokenText() }
+
+func newLexer(r io.Reader) *lexer {
+	lex := &lexer{scan: scanner.Scanner{Mode: scanner.GoTokens}}
+	lex.scan.Init(r)
+	lex.scan.Error = func(s *scanner.Scanner, msg string) { panic(msg) }
+	return lex
+}
+
+func read(lex *lexer) *Value {
+	var v *Value
+	switch lex.token {
+	case scanner.Ident:
+		v = NewSymbol(lex.text())
+	case scanner.String, scanner.RawString:
+		s, err := strconv.Unquote(lex.text())
+		if err != nil {
+			panic(fmt.Sprintf("bad string %s", lex.text()))
+		}
+		v = NewString(s)
+	case scanner.Int:
+		v = &Value{kind: Int, text: lex.text()}
+	case scanner.Float:
+		v = &Value{kind: Float, text: lex.text()}
+	case '-':
+		lex.next()
+		if lex.token != scanner.Int && lex.token != scanner.Float {
+			panic(fmt.Sprintf("unexpected token %q after '-'", lex.text()))
+		}
+		v = read(lex)
+		v.text = "-" + v.text
+		return v
+	case '(':
+		lex.next()
+		v = NewList()
+		for lex.token != ')' {
+			if lex.token == scanner.EOF {
+				panic("end of file")
+			}
+			v.items = append(v.items, read(lex))
+		}
+	default:
+		panic(fmt.Sprintf("unexpected token %q", lex.text()))
+	}
+	lex.next()
+	return v
+}
+
+// Parse decodes a single S-expression
+func Parse(data []byte) (*Value, error) {
+	return Decode(bytes.NewReader(data))
+}
+
+// Decode reads a single S-expression from r
+func Decode(r io.Reader) (v *Value, err error) {
+	lex := newLexer(r)
+	defer func() {
+		if x := recover(); x != nil {
+			err = fmt.Errorf("error at %s: %v", lex.scan.Position, x)
+		}
+	}()
+	lex.next()
+	v = read(lex)
+	if lex.token != scanner.EOF {
+		return nil, fmt.Errorf("error at %s: unexpected token %q after value",
+			lex.scan.Position, lex.text())
+	}
+	return v, nil
+}
+
+// MustParse is like Parse but panics on error
+func MustParse(s string) *Value {
+	v, err := Parse([]byte(s))
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
@@ -0,0 +1,103 @@
+package sexpr
+
+import "testing"
+
+const movie = `((Title "Dr. Strangelove")
+	(Year 1964)
+	(Actor (("Dr. Strangelove" "Peter Sellers")
+		("Gen. Buck Turgidson" "George C. Scott")))
+	(Oscars ("Best Actor" "Best Director" "Best Picture")))`
+
+func TestQuery(t *testing.T) {
+	tree := MustParse(movie)
+	for _, test := range []struct{ path, want string }{
+		{"Title", `"Dr. Strangelove"`},
+		{"Year", "1964"},
+		{`(Actor "Dr. Strangelove")`, `"Peter Sellers"`},
+		{`Actor."Gen. Buck Turgidson"`, `"George C. Scott"`},
+		{"Oscars[2]", `"Best Picture"`},
+		{"(Oscars 0)", `"Best Actor"`},
+	} {
+		got, err := tree.Query(test.path)
+		if err != nil {
+			t.Errorf("Query(%s): %v", test.path, err)
+			continue
+		}
+		if got.String() != test.want {
+			t.Errorf("Query(%s) = %s, want %s", test.path, got, test.want)
+		}
+	}
+	for _, path := range []string{"Sequel", "Oscars[3]", "Year[0]", "Oscars[", "(Actor -1)"} {
+		if got, err := tree.Query(path); err == nil {
+			t.Errorf("Query(%s) = %s, want error", path, got)
+		}
+	}
+}
+
+func TestQueryIntKeys(t *testing.T) {
+	tree := MustParse(`((Ranks ((2 "two") (10 "ten"))) (Pairs ((1 "a") (0 "b"))))`)
+	for _, test := range []struct{ path, want string }{
+		{"(Ranks 2)", `"two"`},
+		{"(Ranks 10)", `"ten"`},
+		{"(Ranks 0)", `(2 "two")`}, // no key 0, an index
+		{"Ranks[1]", `(10 "ten")`},
+		{"(Pairs 0)", `"b"`},
+		{"Pairs[0]", `(1 "a")`},
+	} {
+		got, err := tree.Query(test.path)
+		if err != nil {
+			t.Errorf("Query(%s): %v", test.path, err)
+			continue
+		}
+		if got.String() != test.want {
+			t.Errorf("Query(%s) = %s, want %s", test.path, got, test.want)
+		}
+	}
+	if err := tree.Set("(Ranks 10)", NewString("TEN")); err != nil {
+		t.Fatal(err)
+	}
+	if err := tree.Delete("(Ranks 2)"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := tree.String(), `((Ranks ((10 "TEN"))) (Pairs ((1 "a") (0 "b"))))`; got != want {
+		t.Errorf("after mutation got %s, want %s", got, want)
+	}
+}
+
+func TestMutation(t *testing.T) {
+	tree := MustParse(movie)
+	if err := tree.Set("Year", NewInt(1965)); err != nil {
+		t.Fatal(err)
+	}
+	if err := tree.Set(`(Actor "Maj. T.J. Kong")`, NewString("Slim Pickens")); err != nil {
+		t.Fatal(err)
+	}
+	if err := tree.Set("Oscars[3]", NewString("Best Screenplay")); err != nil {
+		t.Fatal(err)
+	}
+	if err := tree.Delete("Oscars[0]"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tree.Delete("Title"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tree.Set("Color", NewSymbol("false")); err != nil {
+		t.Fatal(err)
+	}
+	want := `((Year 1965) (Actor (("Dr. Strangelove" "Peter Sellers") ("Gen. Buck Turgidson" "George C. Scott") ("Maj. T.J. Kong" "Slim Pickens"))) (Oscars ("Best Director" "Best Picture" "Best Screenplay")) (Color false))`
+	if got := tree.String(); got != want {
+		t.Errorf("after mutation got\n%s\nwant\n%s", got, want)
+	}
+	again, err := Parse([]byte(tree.String()))
+	if err != nil || again.String() != want {
+		t.Errorf("re-encoding round trip = %v, %v", again, err)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, input := range []string{"", "(a b", "a b", ")", `"unterminated`, "- x"} {
+		if v, err := Parse([]byte(input)); err == nil {
+			t.Errorf("Parse(%q) = %s, want error", input, v)
+		}
+	}
+}
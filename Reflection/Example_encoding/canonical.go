@@ -0,0 +1,390 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+/*
+	Canonical S-expressions (Rivest csexp) give every value exactly one byte
+	representation, so the output can be hashed or signed. Every atom is written as
+	<len>:<bytes> and lists as (...), with no whitespace at all:
+
+	((5:Title14:Dr.Strangelove)(4:Year4:1964))
+
+	Map keys are sorted by their own canonical encoding, struct fields keep their
+	declaration order and zero-valued fields are omitted (as in Ejercicio 12.6).
+	Floats are written as -0 and 0 compare, both as 0, and NaN, which equals
+	nothing, is rejected.
+	Pointers and interfaces are wrapped in a list so that nil, (), is never confused
+	with a pointer to an empty value.
+*/
+
+// encodeCanonical writes v in canonical form
+func encodeCanonical(buf *bytes.Buffer, v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Invalid:
+		buf.WriteString("()")
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Int64:
+		writeAtom(buf, strconv.FormatInt(v.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64, reflect.Uintptr:
+		writeAtom(buf, strconv.FormatUint(v.Uint(), 10))
+	case reflect.Float32, reflect.Float64:
+		return writeFloat(buf, v.Float(), v.Type().Bits())
+	case reflect.Complex64, reflect.Complex128:
+		bits := v.Type().Bits() / 2
+		buf.WriteByte('(')
+		if err := writeFloat(buf, real(v.Complex()), bits); err != nil {
+			return err
+		}
+		if err := writeFloat(buf, imag(v.Complex()), bits); err != nil {
+			return err
+		}
+		buf.WriteByte(')')
+	case reflect.Bool:
+		writeAtom(buf, strconv.FormatBool(v.Bool()))
+	case reflect.String:
+		writeAtom(buf, v.String())
+	case reflect.Array, reflect.Slice:
+		buf.WriteByte('(')
+		for i := 0; i < v.Len(); i++ {
+			if err := encodeCanonical(buf, v.Index(i)); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(')')
+	case reflect.Map:
+		keys, err := sortedKeys(v)
+		if err != nil {
+			return err
+		}
+		buf.WriteByte('(')
+		for _, key := range keys {
+			buf.WriteByte('(')
+			if err := encodeCanonical(buf, key); err != nil {
+				return err
+			}
+			if err := encodeCanonical(buf, v.MapIndex(key)); err != nil {
+				return err
+			}
+			buf.WriteByte(')')
+		}
+		buf.WriteByte(')')
+	case reflect.Struct:
+		buf.WriteByte('(')
+		for i := 0; i < v.NumField(); i++ {
+			if !v.Type().Field(i).IsExported() || isZero(v.Field(i)) {
+				continue
+			}
+			buf.WriteByte('(')
+			writeAtom(buf, v.Type().Field(i).Name)
+			if err := encodeCanonical(buf, v.Field(i)); err != nil {
+				return err
+			}
+			buf.WriteByte(')')
+		}
+		buf.WriteByte(')')
+	case reflect.Ptr:
+		buf.WriteByte('(')
+		if !v.IsNil() {
+			if err := encodeCanonical(buf, v.Elem()); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(')')
+	case reflect.Interface:
+		buf.WriteByte('(')
+		if !v.IsNil() {
+			writeAtom(buf, v.Elem().Type().String())
+			if err := encodeCanonical(buf, v.Elem()); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(')')
+	default: // reflect.Chan, reflect.Func, reflect.UnsafePointer
+		return fmt.Errorf("unsupported type: %s", v.Type())
+	}
+	return nil
+}
+
+// writeFloat writes f with -0 as 0, and fails for NaN
+func writeFloat(buf *bytes.Buffer, f float64, bits int) error {
+	if math.IsNaN(f) {
+		return fmt.Errorf("NaN has no canonical form")
+	}
+	if f == 0 {
+		f = 0 // drop the sign of -0
+	}
+	writeAtom(buf, strconv.FormatFloat(f, 'g', -1, bits))
+	return nil
+}
+
+// isZero is reflect.Value.IsZero with -0 taken as zero, as it is encoded
+func isZero(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Complex64, reflect.Complex128:
+		return v.Complex() == 0
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if !isZero(v.Index(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if !isZero(v.Field(i)) {
+				return false
+			}
+		}
+		return true
+	}
+	return v.IsZero()
+}
+
+func writeAtom(buf *bytes.Buffer, s string) {
+	buf.WriteString(strconv.Itoa(len(s)))
+	buf.WriteByte(':')
+	buf.WriteString(s)
+}
+
+// sortedKeys returns the keys of the map v ordered by their canonical encoding
+func sortedKeys(v reflect.Value) ([]reflect.Value, error) {
+	keys := v.MapKeys()
+	encoded := make([][]byte, len(keys))
+	for i, key := range keys {
+		var buf bytes.Buffer
+		if err := encodeCanonical(&buf, key); err != nil {
+			return nil, err
+		}
+		encoded[i] = buf.Bytes()
+	}
+	sort.Sort(byEncoding{keys, encoded})
+	return keys, nil
+}
+
+type byEncoding struct {
+	keys    []reflect.Value
+	encoded [][]byte
+}
+
+func (b byEncoding) Len() int           { return len(b.keys) }
+func (b byEncoding) Less(i, j int) bool { return bytes.Compare(b.encoded[i], b.encoded[j]) < 0 }
+func (b byEncoding) Swap(i, j int) {
+	b.keys[i], b.keys[j] = b.keys[j], b.keys[i]
+	b.encoded[i], b.encoded[j] = b.encoded[j], b.encoded[i]
+}
+
+// marshalCanonical return the canonical encoding of v
+func marshalCanonical(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := encodeCanonical(&buf, reflect.ValueOf(v)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decoder
+
+type canonicalReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *canonicalReader) peek() byte {
+	if r.pos >= len(r.data) {
+		panic("unexpected end of input")
+	}
+	return r.data[r.pos]
+}
+
+func (r *canonicalReader) consume(want byte) {
+	if got := r.peek(); got != want {
+		panic(fmt.Sprintf("got %q, want %q", got, want))
+	}
+	r.pos++
+}
+
+// atom reads a <len>:<bytes> atom
+func (r *canonicalReader) atom() string {
+	start := r.pos
+	for r.peek() >= '0' && r.peek() <= '9' {
+		r.pos++
+	}
+	digits := string(r.data[start:r.pos])
+	if digits == "" {
+		panic(fmt.Sprintf("got %q, want atom length", r.peek()))
+	}
+	if len(digits) > 1 && digits[0] == '0' {
+		panic(fmt.Sprintf("non canonical atom length %s", digits))
+	}
+	r.consume(':')
+	n, err := strconv.Atoi(digits)
+	if err != nil || n > len(r.data)-r.pos {
+		panic(fmt.Sprintf("atom length %s exceeds input", digits))
+	}
+	s := string(r.data[r.pos : r.pos+n])
+	r.pos += n
+	return s
+}
+
+// basicTypes are the dynamic types that can be decoded into an interface
+var basicTypes = map[string]reflect.Type{}
+
+func init() {
+	for _, x := range []interface{}{
+		false, "", int(0), int8(0), int16(0), int32(0), int64(0),
+		uint(0), uint8(0), uint16(0), uint32(0), uint64(0), uintptr(0),
+		float32(0), float64(0), complex64(0), complex128(0),
+	} {
+		t := reflect.TypeOf(x)
+		basicTypes[t.String()] = t
+	}
+}
+
+func readCanonical(r *canonicalReader, v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Int64:
+		i, err := strconv.ParseInt(r.atom(), 10, v.Type().Bits())
+		if err != nil {
+			panic(err)
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64, reflect.Uintptr:
+		u, err := strconv.ParseUint(r.atom(), 10, v.Type().Bits())
+		if err != nil {
+			panic(err)
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(r.atom(), v.Type().Bits())
+		if err != nil {
+			panic(err)
+		}
+		v.SetFloat(f)
+	case reflect.Complex64, reflect.Complex128:
+		bits := v.Type().Bits() / 2
+		r.consume('(')
+		re, err := strconv.ParseFloat(r.atom(), bits)
+		if err != nil {
+			panic(err)
+		}
+		im, err := strconv.ParseFloat(r.atom(), bits)
+		if err != nil {
+			panic(err)
+		}
+		r.consume(')')
+		v.SetComplex(complex(re, im))
+	case reflect.Bool:
+		switch s := r.atom(); s {
+		case "true":
+			v.SetBool(true)
+		case "false":
+			v.SetBool(false)
+		default:
+			panic(fmt.Sprintf("invalid bool %q", s))
+		}
+	case reflect.String:
+		v.SetString(r.atom())
+	case reflect.Array: // (item ...)
+		r.consume('(')
+		for i := 0; r.peek() != ')'; i++ {
+			if i >= v.Len() {
+				panic(fmt.Sprintf("too many elements for %v", v.Type()))
+			}
+			readCanonical(r, v.Index(i))
+		}
+		r.consume(')')
+	case reflect.Slice: // (item ...)
+		r.consume('(')
+		v.Set(reflect.MakeSlice(v.Type(), 0, 0))
+		for r.peek() != ')' {
+			item := reflect.New(v.Type().Elem()).Elem()
+			readCanonical(r, item)
+			v.Set(reflect.Append(v, item))
+		}
+		r.consume(')')
+	case reflect.Map: // ((key value) ...)
+		r.consume('(')
+		v.Set(reflect.MakeMap(v.Type()))
+		for r.peek() != ')' {
+			r.consume('(')
+			key := reflect.New(v.Type().Key()).Elem()
+			readCanonical(r, key)
+			value := reflect.New(v.Type().Elem()).Elem()
+			readCanonical(r, value)
+			v.SetMapIndex(key, value)
+			r.consume(')')
+		}
+		r.consume(')')
+	case reflect.Struct: // ((name value) ...)
+		r.consume('(')
+		for r.peek() != ')' {
+			r.consume('(')
+			name := r.atom()
+			field := v.FieldByName(name)
+			if !field.CanSet() {
+				panic(fmt.Sprintf("unknown field %s in %v", name, v.Type()))
+			}
+			readCanonical(r, field)
+			r.consume(')')
+		}
+		r.consume(')')
+	case reflect.Ptr: // () or (value)
+		r.consume('(')
+		if r.peek() == ')' {
+			v.Set(reflect.Zero(v.Type()))
+		} else {
+			elem := reflect.New(v.Type().Elem())
+			readCanonical(r, elem.Elem())
+			v.Set(elem)
+		}
+		r.consume(')')
+	case reflect.Interface: // () or (type value)
+		r.consume('(')
+		if r.peek() == ')' {
+			v.Set(reflect.Zero(v.Type()))
+		} else {
+			name := r.atom()
+			t, ok := basicTypes[name]
+			if !ok || !t.Implements(v.Type()) {
+				panic(fmt.Sprintf("cannot decode %s into %v", name, v.Type()))
+			}
+			elem := reflect.New(t).Elem()
+			readCanonical(r, elem)
+			v.Set(elem)
+		}
+		r.consume(')')
+	default:
+		panic(fmt.Sprintf("cannot decode into %v", v.Type()))
+	}
+}
+
+// unmarshalCanonical decodes canonical data into the variable pointed to by out
+func unmarshalCanonical(data []byte, out interface{}) (err error) {
+	r := &canonicalReader{data: data}
+	defer func() {
+		if x := recover(); x != nil {
+			err = fmt.Errorf("error at offset %d: %v", r.pos, x)
+		}
+	}()
+	v := reflect.ValueOf(out)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return fmt.Errorf("unmarshal needs a non-nil pointer, got %T", out)
+	}
+	readCanonical(r, v.Elem())
+	if r.pos != len(data) {
+		return fmt.Errorf("error at offset %d: trailing data", r.pos)
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"testing"
+	"testing/quick"
+)
+
+// Types with nested structs, maps, slices, arrays and pointers, for values
+// generated by testing/quick.
+
+type point struct {
+	X, Y  int
+	Label string
+}
+
+type leaf struct {
+	Weights map[string][]float64
+	Corner  *point
+}
+
+type node struct {
+	Name   string
+	Count  int64
+	Ratio  float64
+	Ok     bool
+	Tags   []string
+	Attrs  map[string]int
+	Origin *point
+	Points []point
+	Grid   [2][2]int32
+	Next   *leaf
+	Index  map[int][]*point
+}
+
+func TestCanonicalRoundTrip(t *testing.T) {
+	sequel := "Dr. Strangelove II"
+	x := Movie{
+		Title:  "Dr.Strangelove",
+		Year:   1964,
+		Actor:  map[string]string{`Maj. T.J "King" Kong`: "Slim Pickens", "Dr. Streangelove": "Peter Sellers"},
+		Oscars: []string{"Best Actor (Nomin.)", ""},
+		Sequel: &sequel,
+	}
+	data, err := marshalCanonical(x)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var y Movie
+	if err := unmarshalCanonical(data, &y); err != nil {
+		t.Fatalf("unmarshalCanonical(%s): %v", data, err)
+	}
+	if !reflect.DeepEqual(x, y) {
+		t.Errorf("unmarshalCanonical(marshalCanonical(%+v)) = %+v", x, y)
+	}
+}
+
+// Decoding a canonical encoding and encoding it again gives the same bytes.
+func TestCanonicalStable(t *testing.T) {
+	f := func(x node) bool {
+		data, err := marshalCanonical(x)
+		if err != nil {
+			t.Errorf("marshalCanonical: %v", err)
+			return false
+		}
+		var y node
+		if err := unmarshalCanonical(data, &y); err != nil {
+			t.Errorf("unmarshalCanonical(%s): %v", data, err)
+			return false
+		}
+		again, err := marshalCanonical(y)
+		if err != nil || !bytes.Equal(data, again) {
+			t.Errorf("encoding is not canonical:\n%s\n%s", data, again)
+			return false
+		}
+		return true
+	}
+	if err := quick.Check(f, &quick.Config{MaxCount: 200}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCanonicalMapOrder(t *testing.T) {
+	x := map[string]int{}
+	y := map[string]int{}
+	keys := []string{"gamma", "alpha", "delta", "beta", "epsilon", "zeta"}
+	for i, k := range keys {
+		x[k] = i
+		y[keys[len(keys)-1-i]] = len(keys) - 1 - i
+	}
+	want := "((4:beta1:3)(4:zeta1:5)(5:alpha1:1)(5:delta1:2)(5:gamma1:0)(7:epsilon1:4))"
+	for _, m := range []map[string]int{x, y} {
+		data, err := marshalCanonical(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != want {
+			t.Errorf("marshalCanonical(%v) = %s, want %s", m, data, want)
+		}
+	}
+}
+
+func TestCanonicalFloats(t *testing.T) {
+	negZero := math.Copysign(0, -1)
+	for _, test := range []struct {
+		x    interface{}
+		want string
+	}{
+		{negZero, "1:0"},
+		{[]float64{negZero, 0}, "(1:01:0)"},
+		{complex(negZero, negZero), "(1:01:0)"},
+		{node{Ratio: negZero}, "()"},
+		{struct{ Grid [2]float64 }{[2]float64{negZero}}, "()"},
+		{math.Inf(-1), "4:-Inf"},
+	} {
+		data, err := marshalCanonical(test.x)
+		if err != nil || string(data) != test.want {
+			t.Errorf("marshalCanonical(%v) = %s, %v, want %s", test.x, data, err, test.want)
+		}
+	}
+	for _, x := range []interface{}{math.NaN(), []float32{float32(math.NaN())}, complex(0, math.NaN())} {
+		if data, err := marshalCanonical(x); err == nil {
+			t.Errorf("marshalCanonical(%v) = %s, want an error", x, data)
+		}
+	}
+}
+
+func TestUnmarshalCanonicalErrors(t *testing.T) {
+	var x node
+	for _, input := range []string{
+		"",
+		"(",
+		"((4:Name))",
+		"((4:Nope1:a))",
+		"((5:Count3:abc))",
+		"((4:Name10:short))",
+		"((4:Name01:a))",
+		"((4:Grid((1:1)(1:2)(1:3)))))",
+		"()()",
+	} {
+		if err := unmarshalCanonical([]byte(input), &x); err == nil {
+			t.Errorf("unmarshalCanonical(%q) succeeded, want error", input)
+		}
+	}
+	if err := unmarshalCanonical([]byte("()"), x); err == nil {
+		t.Errorf("unmarshalCanonical into non-pointer succeeded, want error")
+	}
+}
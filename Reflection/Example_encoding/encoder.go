@@ -2,25 +2,44 @@ package main
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"log"
+	"math"
 	"reflect"
+	"strconv"
 )
 
 // encoder
 func encode(buf *bytes.Buffer, v reflect.Value, output string) error {
 	switch v.Kind() {
 	case reflect.Invalid:
-		buf.WriteString("nil")
+		if output == "j" {
+			buf.WriteString("null")
+		} else {
+			buf.WriteString("nil")
+		}
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
 		reflect.Int64:
 		fmt.Fprintf(buf, "%d", v.Int())
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
 		reflect.Uint64:
 		fmt.Fprintf(buf, "%d", v.Uint())
+	case reflect.Float32, reflect.Float64:
+		f := v.Float()
+		if output == "j" && (math.IsNaN(f) || math.IsInf(f, 0)) {
+			return fmt.Errorf("unsupported value: %g", f)
+		}
+		buf.WriteString(strconv.FormatFloat(f, 'g', -1, v.Type().Bits()))
 	case reflect.String:
-		fmt.Fprintf(buf, "%q", v.String())
+		if output == "j" {
+			data, _ := json.Marshal(v.String())
+			buf.Write(data)
+		} else {
+			fmt.Fprintf(buf, "%q", v.String())
+		}
 	case reflect.Array, reflect.Slice:
 		if output == "j" {
 			buf.WriteByte('[')
@@ -55,7 +74,11 @@ func encode(buf *bytes.Buffer, v reflect.Value, output string) error {
 		} else {
 			buf.WriteByte('(')
 		}
-		for i, key := range v.MapKeys() {
+		keys, err := sortedKeys(v)
+		if err != nil {
+			return err
+		}
+		for i, key := range keys {
 			if i > 0 && output != "j" {
 				buf.WriteByte('\t')
 				buf.WriteByte(' ')
@@ -63,7 +86,15 @@ func encode(buf *bytes.Buffer, v reflect.Value, output string) error {
 			if output != "j" {
 				buf.WriteByte('(')
 			}
-			if err := encode(buf, key, output); err != nil {
+			if output == "j" && key.Kind() != reflect.String {
+				// JSON object keys are always strings
+				var kb bytes.Buffer
+				if err := encode(&kb, key, output); err != nil {
+					return err
+				}
+				data, _ := json.Marshal(kb.String())
+				buf.Write(data)
+			} else if err := encode(buf, key, output); err != nil {
 				return err
 			}
 			if output == "j" {
@@ -77,7 +108,7 @@ func encode(buf *bytes.Buffer, v reflect.Value, output string) error {
 			if output != "j" {
 				buf.WriteByte(')')
 			}
-			if i < len(v.MapKeys())-1 {
+			if i < len(keys)-1 {
 				if output == "j" {
 					buf.WriteByte(',')
 				} else {
@@ -98,15 +129,21 @@ func encode(buf *bytes.Buffer, v reflect.Value, output string) error {
 		} else {
 			buf.WriteByte('(')
 		}
+		written := 0
 		for i := 0; i < v.NumField(); i++ {
-			if i > 0 && output != "j" {
-				buf.WriteByte(' ')
-			}
 			// Ejercicio 12.6
 			if v.Field(i).CanInterface() && reflect.Zero(v.Field(i).Type()).CanInterface() &&  
 				reflect.DeepEqual(reflect.Zero(v.Field(i).Type()).Interface(), v.Field(i).Interface()) {
 				continue
 			}
+			if written > 0 {
+				if output == "j" {
+					buf.WriteByte(',')
+				} else {
+					buf.WriteString("\n ")
+				}
+			}
+			written++
 			if output == "j" {
 				fmt.Fprintf(buf, "%q:", v.Type().Field(i).Name)
 			} else {
@@ -118,17 +155,12 @@ func encode(buf *bytes.Buffer, v reflect.Value, output string) error {
 			if output != "j" {
 				buf.WriteByte(')')
 			}
-			if i < v.NumField()-1 {
-				if output == "j" {
-					buf.WriteByte(',')
-				} else {
-					buf.WriteByte('\n')
-				}
-			}
 		}
 		if output == "j" {
 			buf.WriteByte('}')
-		} 
+		} else {
+			buf.WriteByte(')')
+		}
 	case reflect.Bool: // Ejercicio 12.3
 		if v.Bool() {
 			fmt.Fprintf(buf, "true")
@@ -136,18 +168,26 @@ func encode(buf *bytes.Buffer, v reflect.Value, output string) error {
 			fmt.Fprintf(buf, "false")
 		}
 	case reflect.Ptr: // Ejercicio 12.3
-		if v.IsNil() {
-			fmt.Fprintf(buf, "%s", "null")
+		if v.IsNil() && output == "j" {
+			buf.WriteString("null")
+		} else if v.IsNil() {
+			buf.WriteString("nil")
 		} else {
-			fmt.Fprintf(buf, "%q", v.Type().Elem())
+			return encode(buf, v.Elem(), output)
 		}
 
 	case reflect.Complex128, reflect.Complex64: // Ejercicio 12.3
+		if output == "j" {
+			return fmt.Errorf("unsupported type: %s", v.Type())
+		}
 		realpart := real(v.Complex())
 		imgpart := imag(v.Complex())
 		fmt.Fprintf(buf, "#C(%g,%g)", realpart, imgpart)
 
 	case reflect.Interface: // Ejercicio 12.3
+		if output == "j" {
+			return encode(buf, v.Elem(), output)
+		}
 		if v.IsNil() {
 			fmt.Fprintf(buf, "%v", nil)
 		} else {
@@ -184,6 +224,10 @@ func wrapMarshal(v interface{}, output string) (result string, er error) {
 	switch output {
 	case "j":
 		result, er = marshalJSON(v) // Ejercicio 12.5
+	case "c":
+		var data []byte
+		data, er = marshalCanonical(v)
+		result = string(data)
 	default:
 		result, er = marshalString(v) // Ejerciio 12.4
 	}
@@ -223,13 +267,26 @@ func main() {
 		Sequel: nil,
 	}
 
-	flag := "s"
-	if flag == "s" {
+	output := flag.String("o", "s", "output: s (S-expression), j (JSON) or c (canonical)")
+	flag.Parse()
+	if *output == "s" {
 		result, err := wrapMarshal(strangelove, "s")
 		if err != nil {
 			log.Fatal(err)
 		}
 		fmt.Println(result)
+	} else if *output == "c" {
+		var testMovie Movie
+		result, er := wrapMarshal(strangelove, "c")
+		if er != nil {
+			log.Fatal(er)
+		}
+		fmt.Println(result)
+		fmt.Printf("sha256 %x\n", sha256.Sum256([]byte(result)))
+		if err := unmarshalCanonical([]byte(result), &testMovie); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(testMovie)
 	} else {
 		var testMovie Movie
 		result, er := wrapMarshal(strangelove, "j")
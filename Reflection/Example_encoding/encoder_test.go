@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"testing/quick"
+
+	"decoding/sexpr"
+	"equivalence/deep"
+)
+
+// The "s" mode output must decode back to the same value with the Unmarshal of
+// Example_decoding.
+func TestRoundTrip(t *testing.T) {
+	f := func(x node) bool {
+		result, err := marshalString(x)
+		if err != nil {
+			t.Errorf("marshalString: %v", err)
+			return false
+		}
+		var y node
+		if err := sexpr.Unmarshal([]byte(result), &y); err != nil {
+			t.Errorf("Unmarshal(%s): %v", result, err)
+			return false
+		}
+		if !deep.Equal(x, y) {
+			t.Errorf("Unmarshal(marshalString(%+v)) = %+v", x, y)
+			return false
+		}
+		return true
+	}
+	if err := quick.Check(f, &quick.Config{MaxCount: 200}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRoundTripMovie(t *testing.T) {
+	sequel := "Dr. Strangelove II"
+	x := Movie{
+		Title:  "Dr. Strangelove",
+		Year:   1964,
+		Actor:  map[string]string{`Maj. T.J "King" Kong`: "Slim Pickens", "Dr. Streangelove": "Peter Sellers"},
+		Oscars: []string{"Best Actor (Nomin.)", "Best Picture (Nomin.)"},
+		Sequel: &sequel,
+	}
+	result, err := marshalString(x)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var y Movie
+	if err := sexpr.Unmarshal([]byte(result), &y); err != nil {
+		t.Fatalf("Unmarshal(%s): %v", result, err)
+	}
+	if !reflect.DeepEqual(x, y) {
+		t.Errorf("Unmarshal(marshalString(%+v)) = %+v", x, y)
+	}
+}
+
+// The "j" mode output must be JSON that encoding/json accepts and decodes back
+// to the same value.
+func TestJSONMode(t *testing.T) {
+	f := func(x node) bool {
+		result, err := marshalJSON(x)
+		if err != nil {
+			t.Errorf("marshalJSON: %v", err)
+			return false
+		}
+		if !json.Valid([]byte(result)) {
+			t.Errorf("marshalJSON(%+v) is not valid JSON: %s", x, result)
+			return false
+		}
+		var y node
+		if err := json.Unmarshal([]byte(result), &y); err != nil {
+			t.Errorf("json.Unmarshal(%s): %v", result, err)
+			return false
+		}
+		if !deep.Equal(x, y) {
+			t.Errorf("json.Unmarshal(marshalJSON(%+v)) = %+v", x, y)
+			return false
+		}
+		return true
+	}
+	if err := quick.Check(f, &quick.Config{MaxCount: 200}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestJSONModeMovie(t *testing.T) {
+	sequel := "Dr. Strangelove II"
+	x := Movie{
+		Title:  "Dr. Strangelove",
+		Year:   1964,
+		Color:  false,
+		Actor:  map[string]string{`Maj. T.J "King" Kong`: "Slim Pickens"},
+		Oscars: []string{"Best Actor (Nomin.)"},
+		Sequel: &sequel,
+	}
+	result, err := marshalJSON(x)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var y Movie
+	if err := json.Unmarshal([]byte(result), &y); err != nil {
+		t.Fatalf("json.Unmarshal(%s): %v", result, err)
+	}
+	if !reflect.DeepEqual(x, y) {
+		t.Errorf("json.Unmarshal(marshalJSON(%+v)) = %+v", x, y)
+	}
+}
+
+func FuzzUnmarshalCanonical(f *testing.F) {
+	for _, seed := range []string{
+		"()",
+		"((4:Name5:hello)(5:Count2:42)(2:Ok4:true))",
+		"((4:Tags(1:a1:b))(5:Attrs((1:x1:1)))(6:Origin(((1:X2:-1)))))",
+		"((4:Grid((1:1)(1:2)))(4:Next(((6:Corner(())))))(5:Index((1:0((())))))",
+		"((5:Ratio6:1.5e10))",
+	} {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var x node
+		if err := unmarshalCanonical(data, &x); err != nil {
+			return
+		}
+		// whatever was accepted must encode, and the encoding must be stable
+		out, err := marshalCanonical(x)
+		if err != nil {
+			t.Fatalf("marshalCanonical(%+v): %v", x, err)
+		}
+		var y node
+		if err := unmarshalCanonical(out, &y); err != nil {
+			t.Fatalf("unmarshalCanonical(%s): %v", out, err)
+		}
+		again, err := marshalCanonical(y)
+		if err != nil || !bytes.Equal(out, again) {
+			t.Fatalf("encoding is not canonical:\n%s\n%s", out, again)
+		}
+	})
+}
@@ -1,3 +1,13 @@
 module encoder
 
 go 1.19
+
+require (
+	decoding v0.0.0
+	equivalence v0.0.0
+)
+
+replace (
+	decoding => ../Example_decoding
+	equivalence => ../../Low_level_Programming/Deep_equivalence
+)
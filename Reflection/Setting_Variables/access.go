@@ -0,0 +1,384 @@
+package main
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+	Get and Set reach a variable inside a value through a path of field names,
+	indexes and map keys, written as in Go:
+
+	Server.Ports[0]
+	Actor["Dr. Strangelove"]
+	Oscars[1]
+	Limits[cpu]          a key may be left unquoted when it has no ] in it
+
+	Pointers and interfaces are followed on the way. Set starts from a pointer, so
+	every variable it reaches is addressable, allocates the nil pointers and maps it
+	meets and parses the value for the kind of the variable. An index one past the
+	end of a slice appends to it. Map elements are not addressable: Set copies the
+	element, updates the copy and stores it back.
+*/
+
+// PathError records a failed Get or Set and the part of the path where it failed
+type PathError struct {
+	Op   string
+	Path string
+	Err  error
+}
+
+func (e *PathError) Error() string { return e.Op + " " + e.Path + ": " + e.Err.Error() }
+func (e *PathError) Unwrap() error { return e.Err }
+
+// step of a path: a field name, or an index or key between brackets
+type step struct {
+	name  string
+	key   string
+	index bool
+	path  string // path up to and including the step
+}
+
+// parsePath splits a path into its steps
+func parsePath(path string) ([]step, error) {
+	var steps []step
+	rest := path
+	for rest != "" {
+		var s step
+		switch {
+		case rest[0] == '[':
+			end := closing(rest)
+			if end < 0 {
+				return nil, fmt.Errorf("missing ] in %q", path)
+			}
+			s.key, s.index = rest[1:end], true
+			if strings.HasPrefix(s.key, `"`) {
+				key, err := strconv.Unquote(s.key)
+				if err != nil {
+					return nil, fmt.Errorf("bad key %s in %q", s.key, path)
+				}
+				s.key = key
+			}
+			rest = rest[end+1:]
+		default:
+			if rest[0] == '.' {
+				if len(steps) == 0 {
+					return nil, fmt.Errorf("path %q starts with a dot", path)
+				}
+				rest = rest[1:]
+			}
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			s.name, rest = rest[:end], rest[end:]
+			if s.name == "" {
+				return nil, fmt.Errorf("empty field name in %q", path)
+			}
+		}
+		s.path = path[:len(path)-len(rest)]
+		steps = append(steps, s)
+	}
+	if steps == nil {
+		return nil, fmt.Errorf("empty path")
+	}
+	return steps, nil
+}
+
+// closing returns the position of the ] closing the bracket at the start of s,
+// skipping over a quoted key
+func closing(s string) int {
+	if strings.HasPrefix(s, `["`) {
+		for i := 2; i < len(s); i++ {
+			switch s[i] {
+			case '\\':
+				i++
+			case '"':
+				if i+1 < len(s) && s[i+1] == ']' {
+					return i + 1
+				}
+				return -1
+			}
+		}
+		return -1
+	}
+	return strings.IndexByte(s, ']')
+}
+
+// Get returns the value at path inside root
+func Get(root interface{}, path string) (interface{}, error) {
+	steps, err := parsePath(path)
+	if err != nil {
+		return nil, &PathError{"get", path, err}
+	}
+	v := reflect.ValueOf(root)
+	for _, s := range steps {
+		if v, err = get(v, s); err != nil {
+			return nil, &PathError{"get", s.path, err}
+		}
+	}
+	if !v.CanInterface() {
+		return nil, &PathError{"get", path, fmt.Errorf("unexported field")}
+	}
+	return v.Interface(), nil
+}
+
+// indirect follows pointers and interfaces down to a value
+func indirect(v reflect.Value) (reflect.Value, error) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return v, fmt.Errorf("nil %s", v.Type())
+		}
+		v = v.Elem()
+	}
+	return v, nil
+}
+
+func get(v reflect.Value, s step) (reflect.Value, error) {
+	v, err := indirect(v)
+	if err != nil {
+		return v, err
+	}
+	switch {
+	case !s.index:
+		if v.Kind() != reflect.Struct {
+			return v, fmt.Errorf("%s has no fields", v.Type())
+		}
+		f, ok := v.Type().FieldByName(s.name)
+		if !ok {
+			return v, fmt.Errorf("%s has no field %s", v.Type(), s.name)
+		}
+		for _, i := range f.Index[:len(f.Index)-1] { // promoted through embedded structs
+			v = v.Field(i)
+			if v.Kind() == reflect.Ptr {
+				if v.IsNil() {
+					return v, fmt.Errorf("nil embedded %s", v.Type())
+				}
+				v = v.Elem()
+			}
+		}
+		return v.Field(f.Index[len(f.Index)-1]), nil
+	case v.Kind() == reflect.Map:
+		key, err := mapKey(v.Type(), s.key)
+		if err != nil {
+			return v, err
+		}
+		elem := v.MapIndex(key)
+		if !elem.IsValid() {
+			return elem, fmt.Errorf("no key %q", s.key)
+		}
+		return elem, nil
+	case v.Kind() == reflect.Slice || v.Kind() == reflect.Array:
+		i, err := strconv.Atoi(s.key)
+		if err != nil || i < 0 || i >= v.Len() {
+			return v, fmt.Errorf("index %s out of range [0:%d]", s.key, v.Len())
+		}
+		return v.Index(i), nil
+	}
+	return v, fmt.Errorf("%s can not be indexed", v.Type())
+}
+
+// mapKey parses the key of a map of type t
+func mapKey(t reflect.Type, key string) (reflect.Value, error) {
+	k := reflect.New(t.Key()).Elem()
+	if err := parseInto(k, key); err != nil {
+		return k, fmt.Errorf("bad key %q: %v", key, err)
+	}
+	return k, nil
+}
+
+// Set parses value into the variable at path inside the value rootPtr points to
+func Set(rootPtr interface{}, path string, value string) error {
+	steps, err := parsePath(path)
+	if err != nil {
+		return &PathError{"set", path, err}
+	}
+	v := reflect.ValueOf(rootPtr)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return &PathError{"set", path, fmt.Errorf("Set needs a non-nil pointer, got %T", rootPtr)}
+	}
+	if err := set(v.Elem(), steps, value); err != nil {
+		if _, ok := err.(*PathError); !ok {
+			err = &PathError{"set", path, err}
+		}
+		return err
+	}
+	return nil
+}
+
+// set stores value in the variable at steps inside v, which can be set
+func set(v reflect.Value, steps []step, value string) error {
+	if len(steps) == 0 {
+		return parseInto(v, value)
+	}
+	s := steps[0]
+	fail := func(err error) error { return &PathError{"set", s.path, err} }
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return set(v.Elem(), steps, value)
+	case reflect.Interface:
+		if v.IsNil() {
+			return fail(fmt.Errorf("nil %s", v.Type()))
+		}
+		elem := reflect.New(v.Elem().Type()).Elem()
+		elem.Set(v.Elem())
+		if err := set(elem, steps, value); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+	case reflect.Map:
+		if !s.index {
+			break
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		key, err := mapKey(v.Type(), s.key)
+		if err != nil {
+			return fail(err)
+		}
+		elem := reflect.New(v.Type().Elem()).Elem()
+		if old := v.MapIndex(key); old.IsValid() {
+			elem.Set(old)
+		}
+		if err := set(elem, steps[1:], value); err != nil {
+			return err
+		}
+		v.SetMapIndex(key, elem)
+		return nil
+	}
+
+	if !s.index && v.Kind() == reflect.Struct {
+		f, ok := v.Type().FieldByName(s.name)
+		if !ok {
+			return fail(fmt.Errorf("%s has no field %s", v.Type(), s.name))
+		}
+		if !f.IsExported() {
+			return fail(fmt.Errorf("unexported field %s of %s", s.name, v.Type()))
+		}
+		for _, i := range f.Index[:len(f.Index)-1] { // promoted through embedded structs
+			v = v.Field(i)
+			if v.Kind() == reflect.Ptr {
+				if v.IsNil() {
+					if !v.CanSet() {
+						return fail(fmt.Errorf("nil embedded %s", v.Type()))
+					}
+					v.Set(reflect.New(v.Type().Elem()))
+				}
+				v = v.Elem()
+			}
+		}
+		return set(v.Field(f.Index[len(f.Index)-1]), steps[1:], value)
+	}
+
+	if s.index && (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) {
+		i, err := strconv.Atoi(s.key)
+		n := v.Len()
+		if v.Kind() == reflect.Slice && err == nil && i == n {
+			v.Set(reflect.Append(v, reflect.Zero(v.Type().Elem()))) // one past the end appends
+			n++
+		}
+		if err != nil || i < 0 || i >= n {
+			return fail(fmt.Errorf("index %s out of range [0:%d]", s.key, v.Len()))
+		}
+		return set(v.Index(i), steps[1:], value)
+	}
+	if s.index {
+		return fail(fmt.Errorf("%s can not be indexed", v.Type()))
+	}
+	return fail(fmt.Errorf("%s has no fields", v.Type()))
+}
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// parseInto parses value for the kind of v, a slice taking a comma separated list
+func parseInto(v reflect.Value, value string) error {
+	if !v.CanSet() {
+		return fmt.Errorf("%s is not addressable", v.Type())
+	}
+	switch {
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	case reflect.PointerTo(v.Type()).Implements(textUnmarshalerType):
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value))
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(value, 0, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Uintptr:
+		u, err := strconv.ParseUint(value, 0, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Complex64, reflect.Complex128:
+		c, err := strconv.ParseComplex(value, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetComplex(c)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Ptr:
+		elem := reflect.New(v.Type().Elem())
+		if err := parseInto(elem.Elem(), value); err != nil {
+			return err
+		}
+		v.Set(elem)
+	case reflect.Slice:
+		s := reflect.MakeSlice(v.Type(), 0, 0)
+		if value != "" {
+			for _, item := range strings.Split(value, ",") {
+				elem := reflect.New(v.Type().Elem()).Elem()
+				if err := parseInto(elem, strings.TrimSpace(item)); err != nil {
+					return err
+				}
+				s = reflect.Append(s, elem)
+			}
+		}
+		v.Set(s)
+	case reflect.Interface:
+		if v.NumMethod() > 0 {
+			return fmt.Errorf("can not parse into %s", v.Type())
+		}
+		v.Set(reflect.ValueOf(value))
+	default:
+		return fmt.Errorf("can not parse into %s", v.Type())
+	}
+	return nil
+}
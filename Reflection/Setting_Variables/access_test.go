@@ -0,0 +1,153 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+type movie struct {
+	Title  string
+	Actor  map[string]string
+	Oscars []string
+	Sequel *movie
+	Extra  interface{}
+	Scores map[int]*score
+	year   int
+}
+
+type score struct {
+	Value float64
+}
+
+func TestGet(t *testing.T) {
+	m := movie{
+		Title:  "Dr. Strangelove",
+		Actor:  map[string]string{"Dr. Strangelove": "Peter Sellers", "Gen. Buck Turgidson": "George C. Scott"},
+		Oscars: []string{"Best Actor", "Best Picture"},
+		Sequel: &movie{Title: "II"},
+		Extra:  map[string]int{"a]b": 1},
+		year:   1964,
+	}
+	tests := []struct {
+		path string
+		want interface{}
+	}{
+		{`Title`, "Dr. Strangelove"},
+		{`Actor["Dr. Strangelove"]`, "Peter Sellers"},
+		{`Oscars[1]`, "Best Picture"},
+		{`Sequel.Title`, "II"},
+		{`Extra["a]b"]`, 1},
+	}
+	for _, test := range tests {
+		got, err := Get(&m, test.path)
+		if err != nil || got != test.want {
+			t.Errorf("Get(%s) = %v, %v, want %v", test.path, got, err, test.want)
+		}
+	}
+
+	errs := []struct{ path, want string }{
+		{`year`, "get year: unexported field"},
+		{`Oscars[2]`, "get Oscars[2]: index 2 out of range [0:2]"},
+		{`Actor["Muffley"]`, `get Actor["Muffley"]: no key "Muffley"`},
+		{`Sequel.Sequel.Title`, "get Sequel.Sequel.Title: nil *main.movie"},
+		{`Title.Length`, "get Title.Length: string has no fields"},
+		{`Nope`, "get Nope: main.movie has no field Nope"},
+		{`Oscars[x`, `get Oscars[x: missing ] in "Oscars[x"`},
+		{`.Title`, `get .Title: path ".Title" starts with a dot`},
+	}
+	for _, test := range errs {
+		_, err := Get(m, test.path)
+		if err == nil || err.Error() != test.want {
+			t.Errorf("Get(%s) error = %v, want %s", test.path, err, test.want)
+		}
+	}
+}
+
+func TestGetEmbedded(t *testing.T) {
+	type inner struct{ X int }
+	type outer struct{ *inner }
+	if got, err := Get(outer{&inner{X: 7}}, "X"); err != nil || got != 7 {
+		t.Errorf("Get(X) = %v, %v, want 7", got, err)
+	}
+	_, err := Get(outer{}, "X")
+	var pe *PathError
+	if !errors.As(err, &pe) || err.Error() != "get X: nil embedded *main.inner" {
+		t.Errorf("Get(X) through a nil embedded pointer = %v", err)
+	}
+}
+
+func TestSet(t *testing.T) {
+	var m movie
+	sets := []struct{ path, value string }{
+		{`Title`, "Dr. Strangelove"},
+		{`Actor["Dr. Strangelove"]`, "Peter Sellers"},
+		{`Oscars[0]`, "Best Actor"},
+		{`Oscars[1]`, "Best Picture"},
+		{`Oscars[1]`, "Best Director"},
+		{`Sequel.Sequel.Title`, "III"},
+		{`Scores[2].Value`, "4.5"},
+		{`Scores[2].Value`, "5"},
+		{`Extra`, "anything"},
+	}
+	for _, s := range sets {
+		if err := Set(&m, s.path, s.value); err != nil {
+			t.Fatalf("Set(%s, %q): %v", s.path, s.value, err)
+		}
+	}
+	if m.Title != "Dr. Strangelove" || m.Actor["Dr. Strangelove"] != "Peter Sellers" ||
+		strings.Join(m.Oscars, ",") != "Best Actor,Best Director" ||
+		m.Sequel.Sequel.Title != "III" || m.Scores[2].Value != 5 || m.Extra != "anything" {
+		t.Errorf("after Set: %+v", m)
+	}
+
+	type config struct {
+		Timeout time.Duration
+		Ports   []uint16
+		Start   time.Time
+		Labels  map[string][]string
+		Debug   *bool
+	}
+	var c config
+	for _, s := range []struct{ path, value string }{
+		{"Timeout", "1m30s"},
+		{"Ports", "80, 443"},
+		{"Start", "1964-01-29T00:00:00Z"},
+		{"Labels[genre]", "comedy,war"},
+		{"Debug", "true"},
+	} {
+		if err := Set(&c, s.path, s.value); err != nil {
+			t.Errorf("Set(%s, %q): %v", s.path, s.value, err)
+		}
+	}
+	if c.Timeout != 90*time.Second || len(c.Ports) != 2 || c.Ports[1] != 443 || c.Start.Year() != 1964 ||
+		len(c.Labels["genre"]) != 2 || c.Debug == nil || !*c.Debug {
+		t.Errorf("after Set: %+v", c)
+	}
+}
+
+func TestSetErrors(t *testing.T) {
+	var m movie
+	tests := []struct{ path, value, want string }{
+		{`year`, "1964", "set year: unexported field year of main.movie"},
+		{`Oscars[3]`, "x", "set Oscars[3]: index 3 out of range [0:0]"},
+		{`Scores[two].Value`, "1", `set Scores[two]: bad key "two": strconv.ParseInt: parsing "two": invalid syntax`},
+		{`Scores[2].Value`, "high", `set Scores[2].Value: strconv.ParseFloat: parsing "high": invalid syntax`},
+		{`Title.Length`, "1", "set Title.Length: string has no fields"},
+		{`Extra.Field`, "1", "set Extra.Field: nil interface {}"},
+	}
+	for _, test := range tests {
+		err := Set(&m, test.path, test.value)
+		if err == nil || err.Error() != test.want {
+			t.Errorf("Set(%s) error = %v, want %s", test.path, err, test.want)
+		}
+		var pe *PathError
+		if !errors.As(err, &pe) {
+			t.Errorf("Set(%s) error is not a *PathError", test.path)
+		}
+	}
+	if err := Set(m, "Title", "x"); err == nil {
+		t.Errorf("Set of a value that is not a pointer did not fail")
+	}
+}
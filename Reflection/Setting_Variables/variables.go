@@ -1,8 +1,12 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"os"
 	"reflect"
+	"strings"
+	"time"
 )
 
 /*
@@ -32,7 +36,51 @@ import (
 
 */
 
+// Config is a configuration overridden by -set flags
+type Config struct {
+	Name   string
+	Server struct {
+		Host    string
+		Ports   []int
+		Timeout time.Duration
+	}
+	Actor  map[string]string
+	Oscars []string
+	Debug  *bool
+}
+
+// overrides collects the path=value pairs of repeated -set flags
+type overrides [][2]string
+
+func (o *overrides) String() string { return fmt.Sprint(*o) }
+
+func (o *overrides) Set(s string) error {
+	path, value, ok := strings.Cut(s, "=")
+	if !ok {
+		return fmt.Errorf("%q is not path=value", s)
+	}
+	*o = append(*o, [2]string{path, value})
+	return nil
+}
+
 func main(){
+	var sets overrides
+	flag.Var(&sets, "set", "override a field of the configuration, as Server.Ports[0]=8080")
+	flag.Parse()
+	if len(sets) > 0 {
+		config := Config{Name: "strangelove", Oscars: []string{"Best Actor"}}
+		config.Server.Host = "localhost"
+		config.Server.Ports = []int{80}
+		for _, s := range sets {
+			if err := Set(&config, s[0], s[1]); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(2)
+			}
+		}
+		fmt.Printf("%+v\n", config)
+		return
+	}
+
 	x := 2        // value type variable?
 	a := reflect.ValueOf(2) // 2 int no
 	b := reflect.ValueOf(x) // 2 int no